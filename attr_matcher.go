@@ -0,0 +1,320 @@
+package cable
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AttrMatcher matches messages including all the expected attributes:
+// - nested objects are matched partially (i.e., messages could contain extra keys)
+// - arrays match if every expected item matches some of the message array items
+// - keys could be dotted paths (e.g., "data.user.id" or "data.items.0.id")
+// - values could be operator objects: $eq, $ne, $gt, $gte, $lt, $lte, $in, $exists and $regex (with optional $options)
+type AttrMatcher struct {
+	expected map[string]interface{}
+}
+
+// attrOperators contains supported operators; comparison operators are mapped to the expression ones
+var attrOperators = map[string]string{
+	"$eq":      "==",
+	"$ne":      "!=",
+	"$gt":      ">",
+	"$gte":     ">=",
+	"$lt":      "<",
+	"$lte":     "<=",
+	"$in":      "in",
+	"$exists":  "exists",
+	"$regex":   "regex",
+	"$options": "options",
+}
+
+// attrRegexps caches compiled $regex patterns (by pattern and options)
+var attrRegexps = newCompiledCache(maxCompiledCacheSize)
+
+// NewAttrMatcher validates operators and returns the matcher
+func NewAttrMatcher(expected map[string]interface{}) (*AttrMatcher, error) {
+	if err := validateAttrs(expected); err != nil {
+		return nil, err
+	}
+
+	return &AttrMatcher{expected}, nil
+}
+
+func (m *AttrMatcher) Match(msg interface{}) bool {
+	if _, ok := msg.(map[string]interface{}); !ok {
+		return false
+	}
+
+	return matchAttrValue(m.expected, msg, true)
+}
+
+// matchAttrValue matches the actual value against the expected one; found is false when the value is missing
+func matchAttrValue(expected interface{}, actual interface{}, found bool) bool {
+	if ops, ok := expected.(map[string]interface{}); ok && isOperatorObject(ops) {
+		return matchAttrOperators(ops, actual, found)
+	}
+
+	if !found {
+		return expected == nil
+	}
+
+	switch exp := expected.(type) {
+	case nil:
+		return actual == nil
+	case map[string]interface{}:
+		obj, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for k, v := range exp {
+			val, ok := lookupAttr(obj, k)
+			if !matchAttrValue(v, val, ok) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		arr, ok := actual.([]interface{})
+		if !ok {
+			return false
+		}
+
+		// Empty array only matches empty arrays
+		if len(exp) == 0 {
+			return len(arr) == 0
+		}
+
+		for _, item := range exp {
+			if !containsAttrValue(arr, item) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if x, ok := toFloat64(expected); ok {
+		y, ok := toFloat64(actual)
+		return ok && x == y
+	}
+
+	return reflect.DeepEqual(expected, actual)
+}
+
+func containsAttrValue(arr []interface{}, expected interface{}) bool {
+	for _, val := range arr {
+		if matchAttrValue(expected, val, true) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchAttrOperators(ops map[string]interface{}, actual interface{}, found bool) bool {
+	for op, arg := range ops {
+		switch op {
+		case "$exists":
+			if want, _ := arg.(bool); want != found {
+				return false
+			}
+		case "$eq":
+			if !matchAttrValue(arg, actual, found) {
+				return false
+			}
+		case "$ne":
+			if matchAttrValue(arg, actual, found) {
+				return false
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			if !found || !exprCompare(attrOperators[op], actual, arg) {
+				return false
+			}
+		case "$in":
+			if !found || !matchAttrIn(arg, actual) {
+				return false
+			}
+		case "$regex":
+			str, ok := actual.(string)
+			if !found || !ok {
+				return false
+			}
+
+			re, err := attrRegexp(arg, ops["$options"])
+			if err != nil || !re.MatchString(str) {
+				return false
+			}
+		case "$options":
+			// Used by $regex
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchAttrIn returns true if the value (or any of the array items) is in the list
+func matchAttrIn(list interface{}, actual interface{}) bool {
+	items, ok := list.([]interface{})
+	if !ok {
+		return false
+	}
+
+	values, ok := actual.([]interface{})
+	if !ok {
+		values = []interface{}{actual}
+	}
+
+	for _, val := range values {
+		for _, item := range items {
+			if matchAttrValue(item, val, true) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func attrRegexp(pattern interface{}, options interface{}) (*regexp.Regexp, error) {
+	str, ok := pattern.(string)
+	if !ok {
+		return nil, fmt.Errorf("$regex must be a string, got %v", pattern)
+	}
+
+	flags := ""
+	if options != nil {
+		opts, ok := options.(string)
+		if !ok || strings.Trim(opts, "ims") != "" {
+			return nil, fmt.Errorf("$options must contain only i, m or s flags, got %v", options)
+		}
+		flags = opts
+	}
+
+	key := flags + "/" + str
+
+	if re, ok := attrRegexps.get(key); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	if flags != "" {
+		str = "(?" + flags + ")" + str
+	}
+
+	re, err := regexp.Compile(str)
+	if err != nil {
+		return nil, err
+	}
+
+	attrRegexps.put(key, re)
+
+	return re, nil
+}
+
+// lookupAttr returns the value by the key or the dotted path (array items are accessed by indices)
+func lookupAttr(obj map[string]interface{}, key string) (interface{}, bool) {
+	if val, ok := obj[key]; ok {
+		return val, true
+	}
+
+	if !strings.Contains(key, ".") {
+		return nil, false
+	}
+
+	var val interface{} = obj
+
+	for _, part := range strings.Split(key, ".") {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			next, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			val = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			val = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+func isOperatorObject(obj map[string]interface{}) bool {
+	if len(obj) == 0 {
+		return false
+	}
+
+	for k := range obj {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateAttrs checks operators (and compiles regular expressions)
+func validateAttrs(val interface{}) error {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		operators := 0
+
+		for k, item := range v {
+			if !strings.HasPrefix(k, "$") {
+				if err := validateAttrs(item); err != nil {
+					return err
+				}
+				continue
+			}
+
+			operators++
+
+			if _, ok := attrOperators[k]; !ok {
+				return fmt.Errorf("unknown matcher operator: %s", k)
+			}
+
+			switch k {
+			case "$in":
+				if _, ok := item.([]interface{}); !ok {
+					return fmt.Errorf("$in must be an array, got %v", item)
+				}
+			case "$exists":
+				if _, ok := item.(bool); !ok {
+					return fmt.Errorf("$exists must be a boolean, got %v", item)
+				}
+			case "$regex":
+				if _, err := attrRegexp(item, v["$options"]); err != nil {
+					return fmt.Errorf("invalid $regex: %w", err)
+				}
+			case "$options":
+				if _, ok := v["$regex"]; !ok {
+					return fmt.Errorf("$options must be used with $regex")
+				}
+			}
+		}
+
+		if operators > 0 && operators != len(v) {
+			return fmt.Errorf("matcher operators can't be mixed with attributes")
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := validateAttrs(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
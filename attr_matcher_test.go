@@ -0,0 +1,93 @@
+package cable
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttrMatcher(t *testing.T) {
+	msg := map[string]interface{}{
+		"type": "update",
+		"data": map[string]interface{}{
+			"user":  map[string]interface{}{"id": int8(1), "name": "jack", "role": "admin"},
+			"tags":  []interface{}{"a", "b", "c"},
+			"items": []interface{}{map[string]interface{}{"id": float64(1), "qty": float64(5)}, map[string]interface{}{"id": float64(2), "qty": float64(0)}},
+		},
+		"score":     float64(42),
+		"empty":     nil,
+		"dotted.id": "literal",
+	}
+
+	cases := []struct {
+		attrs    map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"type": "update"}, true},
+		{map[string]interface{}{"data": map[string]interface{}{"user": map[string]interface{}{"id": float64(1)}}}, true},
+		{map[string]interface{}{"data": map[string]interface{}{"user": map[string]interface{}{"id": float64(2)}}}, false},
+		{map[string]interface{}{"data.user.id": float64(1)}, true},
+		{map[string]interface{}{"data.user.missing": float64(1)}, false},
+		{map[string]interface{}{"data.items.1.id": float64(2)}, true},
+		{map[string]interface{}{"dotted.id": "literal"}, true},
+		{map[string]interface{}{"data": map[string]interface{}{"tags": []interface{}{"c", "a"}}}, true},
+		{map[string]interface{}{"data": map[string]interface{}{"tags": []interface{}{"d"}}}, false},
+		{map[string]interface{}{"data": map[string]interface{}{"tags": []interface{}{}}}, false},
+		{map[string]interface{}{"data.items": []interface{}{map[string]interface{}{"qty": float64(0)}}}, true},
+		{map[string]interface{}{"score": map[string]interface{}{"$gt": float64(40), "$lte": float64(42)}}, true},
+		{map[string]interface{}{"score": map[string]interface{}{"$lt": float64(42)}}, false},
+		{map[string]interface{}{"score": map[string]interface{}{"$gte": float64(42)}}, true},
+		{map[string]interface{}{"data.user.name": map[string]interface{}{"$regex": "^ja"}}, true},
+		{map[string]interface{}{"data.user.name": map[string]interface{}{"$regex": "^JA"}}, false},
+		{map[string]interface{}{"data.user.name": map[string]interface{}{"$regex": "^JA", "$options": "i"}}, true},
+		{map[string]interface{}{"data.user.role": map[string]interface{}{"$in": []interface{}{"admin", "owner"}}}, true},
+		{map[string]interface{}{"data.user.role": map[string]interface{}{"$in": []interface{}{"guest"}}}, false},
+		{map[string]interface{}{"data.tags": map[string]interface{}{"$in": []interface{}{"z", "b"}}}, true},
+		{map[string]interface{}{"data.user.email": map[string]interface{}{"$exists": false}}, true},
+		{map[string]interface{}{"data.user.email": map[string]interface{}{"$exists": true}}, false},
+		{map[string]interface{}{"empty": map[string]interface{}{"$exists": true}}, true},
+		{map[string]interface{}{"empty": nil}, true},
+		{map[string]interface{}{"type": map[string]interface{}{"$ne": "create"}}, true},
+		{map[string]interface{}{"data.items": []interface{}{map[string]interface{}{"qty": map[string]interface{}{"$gt": float64(3)}}}}, true},
+		{map[string]interface{}{"data.items": []interface{}{map[string]interface{}{"qty": map[string]interface{}{"$gt": float64(10)}}}}, false},
+	}
+
+	for _, c := range cases {
+		m, err := NewAttrMatcher(c.attrs)
+		require.NoError(t, err, c.attrs)
+		assert.Equal(t, c.expected, m.Match(msg), c.attrs)
+	}
+
+	m, err := NewAttrMatcher(map[string]interface{}{"type": "update"})
+	require.NoError(t, err)
+	assert.False(t, m.Match("update"))
+}
+
+func TestAttrMatcherValidation(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"unknown matcher operator: $foo":                   {"a": map[string]interface{}{"$foo": 1}},
+		"$in must be an array":                             {"a": map[string]interface{}{"$in": 1}},
+		"$exists must be a boolean":                        {"a": map[string]interface{}{"$exists": "yes"}},
+		"invalid $regex":                                   {"a": map[string]interface{}{"$regex": "("}},
+		"$options must be used with $regex":                {"a": map[string]interface{}{"$options": "i"}},
+		"$options must contain only i, m or s flags":       {"a": map[string]interface{}{"$regex": "a", "$options": "x"}},
+		"matcher operators can't be mixed with attributes": {"a": map[string]interface{}{"$gt": 1, "b": 2}},
+	}
+
+	for expected, attrs := range cases {
+		_, err := NewAttrMatcher(attrs)
+		require.Error(t, err, expected)
+		assert.Contains(t, err.Error(), expected)
+	}
+}
+
+func TestAttrRegexpCacheIsBounded(t *testing.T) {
+	for i := 0; i < maxCompiledCacheSize*2; i++ {
+		_, err := attrRegexp("^user-"+strconv.Itoa(i)+"$", "i")
+		require.NoError(t, err)
+	}
+
+	assert.LessOrEqual(t, attrRegexps.len(), maxCompiledCacheSize)
+}
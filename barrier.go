@@ -0,0 +1,145 @@
+package cable
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	barrierReleased = "released"
+	barrierTimeout  = "timeout"
+)
+
+// barrierRegistry contains barriers shared by all the VUs of the process
+type barrierRegistry struct {
+	mu       sync.Mutex
+	barriers map[string]*barrier
+}
+
+func newBarrierRegistry() *barrierRegistry {
+	return &barrierRegistry{barriers: make(map[string]*barrier)}
+}
+
+func (r *barrierRegistry) get(name string, count int) (*barrier, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.barriers[name]
+	if !ok {
+		b = &barrier{name: name, count: count, releaseCh: make(chan struct{})}
+		r.barriers[name] = b
+	}
+
+	if b.count != count {
+		return nil, fmt.Errorf("barrier %s is already used with %d participants", name, b.count)
+	}
+
+	return b, nil
+}
+
+// barrier blocks participants until the expected number of them arrive.
+// Barriers are cyclic: once released, the next participants wait for the next generation.
+type barrier struct {
+	name  string
+	count int
+
+	mu        sync.Mutex
+	arrived   int
+	releaseCh chan struct{}
+}
+
+// wait blocks until all the participants arrive; returns false if the timeout is reached (or the context is done)
+func (b *barrier) wait(ctx context.Context, timeout time.Duration) bool {
+	b.mu.Lock()
+
+	b.arrived++
+	releaseCh := b.releaseCh
+
+	if b.arrived == b.count {
+		b.arrived = 0
+		b.releaseCh = make(chan struct{})
+		close(releaseCh)
+		b.mu.Unlock()
+
+		return true
+	}
+
+	b.mu.Unlock()
+
+	var timeoutCh <-chan time.Time
+
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		timeoutCh = timer.C
+	}
+
+	select {
+	case <-releaseCh:
+		return true
+	case <-timeoutCh:
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The barrier could be released while we were acquiring the lock
+	select {
+	case <-releaseCh:
+		return true
+	default:
+	}
+
+	b.arrived--
+
+	return false
+}
+
+// Barrier blocks the VU until count participants (VUs) arrive at the barrier with the same name.
+// Returns false if the timeout is reached (timeoutMs <= 0 means waiting until the iteration is interrupted).
+func (c *Cable) Barrier(name string, count int, timeoutMs int) (bool, error) {
+	state := c.vu.State()
+	if state == nil {
+		return false, errCableInInitContext
+	}
+
+	if name == "" {
+		return false, fmt.Errorf("barrier name is required")
+	}
+
+	if count < 1 {
+		return false, fmt.Errorf("barrier participants count must be positive, got %d", count)
+	}
+
+	b, err := c.root.barriers.get(name, count)
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	released := b.wait(c.vu.Context(), time.Duration(timeoutMs)*time.Millisecond)
+	now := time.Now()
+
+	result := barrierReleased
+	if !released {
+		result = barrierTimeout
+		state.Logger.Warnf("barrier %s timed out waiting for %d participants", name, count)
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.BarrierWait,
+			Tags:   state.Tags.GetCurrentValues().Tags.With("barrier", name).With("result", result),
+		},
+		Time:  now,
+		Value: metrics.D(now.Sub(start)),
+	})
+
+	return released, nil
+}
@@ -0,0 +1,76 @@
+package cable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarrier(t *testing.T) {
+	ts := newTestState(t)
+
+	// Another participant arrives later
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+
+		b, err := ts.module.root.barriers.get("start", 2)
+		if assert.NoError(t, err) {
+			assert.True(t, b.wait(context.Background(), time.Second))
+		}
+	}()
+
+	released := ts.run(t, `cable.barrier("start", 2, 1000)`).ToBoolean()
+	assert.True(t, released)
+
+	wait := ts.requireMetric(t, "cable_barrier_wait", map[string]string{"barrier": "start", "result": "released"})
+	assert.GreaterOrEqual(t, wait[0].Value, 40.0)
+
+	_, err := ts.VU.Runtime().RunString(`cable.barrier("start", 3)`)
+	assert.ErrorContains(t, err, "barrier start is already used with 2 participants")
+}
+
+func TestBarrierTimeout(t *testing.T) {
+	ts := newTestState(t)
+
+	released := ts.run(t, `cable.barrier("lonely", 2, 50)`).ToBoolean()
+	assert.False(t, released)
+
+	ts.requireMetric(t, "cable_barrier_wait", map[string]string{"barrier": "lonely", "result": "timeout"})
+
+	// Timed out participants leave the barrier
+	b, err := ts.module.root.barriers.get("lonely", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 0, b.arrived)
+}
+
+func TestBarrierGenerations(t *testing.T) {
+	b, err := newBarrierRegistry().get("cyclic", 2)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		done := make(chan bool)
+
+		go func() { done <- b.wait(context.Background(), time.Second) }()
+
+		assert.True(t, b.wait(context.Background(), time.Second))
+		assert.True(t, <-done)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(t, b.wait(ctx, 0))
+}
+
+func TestBarrierValidation(t *testing.T) {
+	ts := newTestState(t)
+
+	_, err := ts.VU.Runtime().RunString(`cable.barrier("", 2)`)
+	assert.ErrorContains(t, err, "barrier name is required")
+
+	_, err = ts.VU.Runtime().RunString(`cable.barrier("start", 0)`)
+	assert.ErrorContains(t, err, "barrier participants count must be positive")
+}
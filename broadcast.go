@@ -0,0 +1,260 @@
+package cable
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	// broadcastTimestampKey is the data key containing the send time (Unix ms);
+	// it's removed from incoming messages and used to track the broadcast latency
+	broadcastTimestampKey = "__sent_at__"
+
+	defaultBroadcastTimeoutMs = 10000
+)
+
+// broadcastOptions contains the options passed as the last argument of cable.broadcast
+type broadcastOptions struct {
+	// Secret is the broadcasting secret (sent as a bearer token)
+	Secret string `json:"secret"`
+	// Tags are added to the broadcast metrics
+	Tags map[string]string `json:"tags"`
+	// ExcludeSocket is the session ID of the client which shouldn't receive the broadcast (broadcast_to_others)
+	ExcludeSocket string `json:"excludeSocket"`
+	// Timestamp stamps object payloads with the send time (enabled by default)
+	Timestamp *bool `json:"timestamp"`
+	// TimeoutMs is the HTTP request timeout
+	TimeoutMs int `json:"timeoutMs"`
+}
+
+// broadcastMessage is the AnyCable broadcast message; data is JSON-encoded
+type broadcastMessage struct {
+	Stream string         `json:"stream"`
+	Data   string         `json:"data"`
+	Meta   *broadcastMeta `json:"meta,omitempty"`
+}
+
+type broadcastMeta struct {
+	ExcludeSocket string `json:"exclude_socket,omitempty"`
+}
+
+// broadcastInput is the batch item
+type broadcastInput struct {
+	Stream string      `json:"stream"`
+	Data   interface{} `json:"data"`
+}
+
+// Broadcast publishes the data to the stream via the AnyCable HTTP broadcasting endpoint.
+// Use an array of {stream, data} objects instead of the stream to publish multiple messages at once
+// (options are passed as the third argument in this case).
+func (c *Cable) Broadcast(url string, streamIn sobek.Value, dataIn sobek.Value, optsIn sobek.Value) error {
+	state := c.vu.State()
+	if state == nil {
+		return errCableInInitContext
+	}
+
+	rt := c.vu.Runtime()
+	batch := isBatch(streamIn)
+
+	var inputs []broadcastInput
+
+	if batch {
+		raw, err := json.Marshal(streamIn.Export())
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(raw, &inputs); err != nil {
+			return fmt.Errorf("invalid broadcast batch: %w", err)
+		}
+
+		optsIn = dataIn
+	} else {
+		if streamIn == nil || sobek.IsUndefined(streamIn) || sobek.IsNull(streamIn) {
+			return fmt.Errorf("broadcast stream is required")
+		}
+
+		var data interface{}
+		if dataIn != nil {
+			data = dataIn.Export()
+		}
+
+		inputs = []broadcastInput{{Stream: streamIn.String(), Data: data}}
+	}
+
+	var opts broadcastOptions
+	if err := decodeOptions(rt, optsIn, &opts); err != nil {
+		return err
+	}
+
+	if len(inputs) == 0 {
+		return fmt.Errorf("broadcast batch is empty")
+	}
+
+	stamp := opts.Timestamp == nil || *opts.Timestamp
+	sentAt := time.Now()
+
+	messages := make([]*broadcastMessage, len(inputs))
+
+	for i, input := range inputs {
+		if input.Stream == "" {
+			return fmt.Errorf("broadcast stream is required")
+		}
+
+		msg, err := newBroadcastMessage(input, &opts, stamp, sentAt)
+		if err != nil {
+			return err
+		}
+
+		messages[i] = msg
+	}
+
+	// Single messages are sent as objects, batches as arrays
+	var payload interface{} = messages
+	if !batch {
+		payload = messages[0]
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	status, err := c.postBroadcast(url, body, &opts)
+
+	tags := state.Tags.GetCurrentValues().Tags.With("status", fmt.Sprintf("%d", status))
+	for k, v := range opts.Tags {
+		tags = tags.With(k, v)
+	}
+
+	now := time.Now()
+
+	metrics.PushIfNotDone(c.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.BroadcastDuration,
+			Tags:   tags,
+		},
+		Time:  now,
+		Value: metrics.D(now.Sub(sentAt)),
+	})
+
+	return err
+}
+
+func isBatch(val sobek.Value) bool {
+	if val == nil || sobek.IsUndefined(val) || sobek.IsNull(val) {
+		return false
+	}
+
+	_, ok := val.Export().([]interface{})
+
+	return ok
+}
+
+func newBroadcastMessage(input broadcastInput, opts *broadcastOptions, stamp bool, sentAt time.Time) (*broadcastMessage, error) {
+	data := input.Data
+
+	if obj, ok := data.(map[string]interface{}); ok && stamp {
+		stamped := make(map[string]interface{}, len(obj)+1)
+		for k, v := range obj {
+			stamped[k] = v
+		}
+
+		stamped[broadcastTimestampKey] = float64(sentAt.UnixMicro()) / 1000
+		data = stamped
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode broadcast data: %w", err)
+	}
+
+	msg := &broadcastMessage{Stream: input.Stream, Data: string(encoded)}
+
+	if opts.ExcludeSocket != "" {
+		msg.Meta = &broadcastMeta{ExcludeSocket: opts.ExcludeSocket}
+	}
+
+	return msg, nil
+}
+
+// postBroadcast performs the broadcasting request and returns the response status (0 if the request failed)
+func (c *Cable) postBroadcast(url string, body []byte, opts *broadcastOptions) (int, error) {
+	timeout := opts.TimeoutMs
+	if timeout <= 0 {
+		timeout = defaultBroadcastTimeoutMs
+	}
+
+	client := &http.Client{
+		Transport: c.vu.State().Transport,
+		Timeout:   time.Duration(timeout) * time.Millisecond,
+	}
+
+	req, err := http.NewRequestWithContext(c.vu.Context(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if opts.Secret != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Secret)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("broadcast failed with status: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// trackBroadcastLatency removes the send timestamp from the message data and tracks the broadcast latency
+func (c *Client) trackBroadcastLatency(msg *cableMsg, data map[string]interface{}) {
+	val, ok := data[broadcastTimestampKey]
+	if !ok {
+		return
+	}
+
+	delete(data, broadcastTimestampKey)
+
+	ms, ok := toFloat64(val)
+	if !ok {
+		return
+	}
+
+	sentAt := time.UnixMicro(int64(ms * 1000))
+
+	latency := msg.receivedAt.Sub(sentAt)
+	if latency < 0 {
+		latency = 0
+	}
+
+	tags := c.sampleTags
+	if name := c.channelName(msg.Identifier); name != "" {
+		tags = tags.With("channel", name)
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.BroadcastLatency,
+			Tags:   tags,
+		},
+		Time:  msg.receivedAt,
+		Value: metrics.D(latency),
+	})
+}
@@ -0,0 +1,126 @@
+package cable
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type broadcastRequest struct {
+	auth string
+	body []byte
+}
+
+func startBroadcastServer(t *testing.T, status int) (*httptest.Server, func() []broadcastRequest) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		requests []broadcastRequest
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, broadcastRequest{auth: r.Header.Get("Authorization"), body: body})
+		mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, func() []broadcastRequest {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]broadcastRequest(nil), requests...)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	ts := newTestState(t)
+	server, requests := startBroadcastServer(t, http.StatusCreated)
+
+	require.NoError(t, ts.VU.Runtime().Set("BROADCAST_URL", server.URL+"/_broadcast"))
+
+	ts.run(t, `
+		cable.broadcast(BROADCAST_URL, "chat_1", { text: "hello" }, { secret: "s3cr3t", excludeSocket: "sid-42", tags: { kind: "chat" } });
+		cable.broadcast(BROADCAST_URL, [{ stream: "chat_1", data: "plain" }, { stream: "chat_2", data: { text: "bye" } }], { timestamp: false });
+	`)
+
+	reqs := requests()
+	require.Len(t, reqs, 2)
+
+	assert.Equal(t, "Bearer s3cr3t", reqs[0].auth)
+
+	var single broadcastMessage
+	require.NoError(t, json.Unmarshal(reqs[0].body, &single))
+
+	assert.Equal(t, "chat_1", single.Stream)
+	require.NotNil(t, single.Meta)
+	assert.Equal(t, "sid-42", single.Meta.ExcludeSocket)
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(single.Data), &data))
+
+	assert.Equal(t, "hello", data["text"])
+	assert.InDelta(t, float64(time.Now().UnixMilli()), data[broadcastTimestampKey], 5000)
+
+	assert.Empty(t, reqs[1].auth)
+
+	var batch []broadcastMessage
+	require.NoError(t, json.Unmarshal(reqs[1].body, &batch))
+
+	require.Len(t, batch, 2)
+	assert.Equal(t, broadcastMessage{Stream: "chat_1", Data: `"plain"`}, batch[0])
+	assert.Equal(t, broadcastMessage{Stream: "chat_2", Data: `{"text":"bye"}`}, batch[1])
+
+	ts.requireMetric(t, "cable_broadcast_duration", map[string]string{"status": "201", "kind": "chat"})
+}
+
+func TestBroadcastFailure(t *testing.T) {
+	ts := newTestState(t)
+	server, _ := startBroadcastServer(t, http.StatusUnauthorized)
+
+	require.NoError(t, ts.VU.Runtime().Set("BROADCAST_URL", server.URL+"/_broadcast"))
+
+	_, err := ts.VU.Runtime().RunString(`cable.broadcast(BROADCAST_URL, "chat_1", { text: "hello" })`)
+	assert.ErrorContains(t, err, "broadcast failed with status: 401")
+
+	ts.requireMetric(t, "cable_broadcast_duration", map[string]string{"status": "401"})
+
+	_, err = ts.VU.Runtime().RunString(`cable.broadcast(BROADCAST_URL, [])`)
+	assert.ErrorContains(t, err, "broadcast batch is empty")
+
+	_, err = ts.VU.Runtime().RunString(`cable.broadcast(BROADCAST_URL, "chat_1", {}, { unknown: true })`)
+	assert.ErrorContains(t, err, `unknown field "unknown"`)
+}
+
+func TestBroadcastLatency(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+	`)
+
+	sentAt := float64(time.Now().Add(-20*time.Millisecond).UnixMicro()) / 1000
+	server.Broadcast("EchoChannel", map[string]interface{}{"text": "hello", broadcastTimestampKey: sentAt})
+
+	msg := ts.run(t, `channel.receive()`).Export().(map[string]interface{})
+	assert.Equal(t, "hello", msg["text"])
+	assert.NotContains(t, msg, broadcastTimestampKey)
+
+	latency := ts.requireMetric(t, "cable_broadcast_latency", map[string]string{"channel": "EchoChannel"})
+	assert.GreaterOrEqual(t, latency[0].Value, 20.0)
+}
@@ -1,12 +1,14 @@
 package cable
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.k6.io/k6/js/common"
@@ -33,46 +35,81 @@ func (c *Cable) Connect(cableUrl string, opts sobek.Value) (*Client, error) {
 		return nil, err
 	}
 
-	wsd := createDialer(state, cOpts.handshakeTimeout())
-	connectionStart := time.Now()
-
-	headers := cOpts.header()
+	if _, err := cOpts.compression(); err != nil {
+		return nil, err
+	}
 
-	if headers.Get("ORIGIN") == "" {
-		uri, perr := url.Parse(cableUrl)
+	if _, err := cOpts.pusher(); err != nil {
+		return nil, err
+	}
 
-		if perr == nil {
-			var scheme string
+	logger := createLogger(state, cOpts)
 
-			if uri.Scheme == "wss" {
-				scheme = "https"
-			} else {
-				scheme = "http"
-			}
+	client, err := c.connect(cableUrl, cOpts, logger)
+	if err != nil {
+		logger.Errorf("%v", err)
+		return nil, nil
+	}
 
-			origin := fmt.Sprintf("%s://%s", scheme, uri.Host)
+	return client, nil
+}
 
-			headers.Set("ORIGIN", origin)
-		}
+// connect establishes a WebSocket connection and returns the started client.
+// Options must be validated beforehand.
+func (c *Cable) connect(cableUrl string, cOpts *connectOptions, logger *logrus.Entry) (*Client, error) {
+	client, _, err := c.dial(cableUrl, cOpts, logger)
+	if err != nil {
+		return nil, err
 	}
 
-	if cOpts.codec() == JSONCodec {
-		headers.Set("Sec-WebSocket-Protocol", "actioncable-v1-json")
-	} else if cOpts.codec() == MsgPackCodec {
-		headers.Set("Sec-WebSocket-Protocol", "actioncable-v1-msgpack")
-	} else if cOpts.codec() == ProtobufCodec {
-		headers.Set("Sec-WebSocket-Protocol", "actioncable-v1-protobuf")
+	if err := client.start(); err != nil {
+		_ = client.transport.Close()
+		return nil, fmt.Errorf("failed to initialize Action Cable connection: %w", err)
 	}
 
-	level, err := logrus.ParseLevel(cOpts.LogLevel)
+	return client, nil
+}
 
-	if err == nil {
-		if logger, ok := state.Logger.(*logrus.Logger); ok {
-			logger.SetLevel(level)
+// dial establishes a WebSocket connection and returns the client (not started yet)
+// along with the handshake response.
+func (c *Cable) dial(cableUrl string, cOpts *connectOptions, logger *logrus.Entry) (*Client, *http.Response, error) {
+	state := c.vu.State()
+
+	compression, _ := cOpts.compression()
+	pusher, _ := cOpts.pusher()
+
+	wsd := createDialer(state, cOpts.handshakeTimeout(), compression)
+
+	var netConn *meteredConn
+
+	if compression {
+		// Track the underlying connection to measure the size of compressed messages
+		wsd.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, derr := state.Dialer.DialContext(ctx, network, addr)
+			if derr != nil {
+				return nil, derr
+			}
+			netConn = &meteredConn{Conn: conn}
+			return netConn, nil
 		}
 	}
 
-	logger := state.Logger.WithField("source", "cable")
+	connectionStart := time.Now()
+
+	headers := cOpts.header()
+
+	setOrigin(headers, cableUrl)
+
+	// Pusher protocol doesn't use subprotocols
+	if !pusher {
+		if cOpts.codec() == JSONCodec {
+			headers.Set("Sec-WebSocket-Protocol", "actioncable-v1-json")
+		} else if cOpts.codec() == MsgPackCodec {
+			headers.Set("Sec-WebSocket-Protocol", "actioncable-v1-msgpack")
+		} else if cOpts.codec() == ProtobufCodec {
+			headers.Set("Sec-WebSocket-Protocol", "actioncable-v1-protobuf")
+		}
+	}
 
 	conn, httpResponse, connErr := wsd.DialContext(c.vu.Context(), cableUrl, headers)
 	connectionEnd := time.Now()
@@ -123,34 +160,99 @@ func (c *Cable) Connect(cableUrl string, opts sobek.Value) (*Client, error) {
 	})
 
 	if connErr != nil {
-		logger.Errorf("failed to connect: %v", connErr)
-		return nil, nil
+		return nil, httpResponse, fmt.Errorf("failed to connect: %w", connErr)
+	}
+
+	var t transport = &wsTransport{conn: conn, codec: cOpts.codec()}
+	if pusher {
+		t = newPusherTransport(conn, cOpts.Pusher)
 	}
 
-	client := Client{
-		vu:            c.vu,
-		conn:          conn,
-		codec:         cOpts.codec(),
-		logger:        logger,
-		channels:      make(map[string]*Channel),
-		readCh:        make(chan *cableMsg, 1024),
-		errorCh:       make(chan error, 1024),
-		closeCh:       make(chan int, 1),
-		recTimeout:    cOpts.receiveTimeout(),
-		sampleTags:    tagsAndMeta.Tags,
-		samplesOutput: state.Samples,
+	client := c.newClient(t, cableUrl, headers, cOpts, logger, tagsAndMeta.Tags)
+
+	if compression && netConn != nil && compressionNegotiated(httpResponse) {
+		client.compressed = true
+		client.netConn = netConn
+	} else if compression {
+		logger.Warnf("server doesn't support permessage-deflate compression")
 	}
 
-	err = client.start()
+	return client, httpResponse, nil
+}
+
+func (c *Cable) newClient(t transport, cableUrl string, header http.Header, cOpts *connectOptions, logger *logrus.Entry, tags *metrics.TagSet) *Client {
+	vuID := c.vu.State().VUID
+
+	rec, err := newRecorder(cOpts, vuID)
 	if err != nil {
-		logger.Errorf("failed to initialize Action Cable connection: %v", err)
-		return nil, nil
+		logger.Errorf("recording is disabled: %v", err)
+	} else if rec != nil {
+		rec.connect(cableUrl, header, cOpts.RecordRedact, vuID)
+		t = withRecorder(t, rec)
+	}
+
+	raw := newRawTap()
+
+	if rt, ok := t.(rawTransport); ok {
+		rt.setTap(raw)
+	}
+
+	return &Client{
+		vu:                c.vu,
+		transport:         t,
+		metrics:           c.metrics,
+		trackers:          c.root.trackers,
+		scriptDir:         c.scriptDir,
+		logger:            logger,
+		channels:          make(map[string]*Channel),
+		readCh:            make(chan *cableMsg, 1024),
+		errorCh:           make(chan error, 1024),
+		closeCh:           make(chan int, 1),
+		raw:               raw,
+		closedCh:          make(chan struct{}),
+		recTimeout:        cOpts.receiveTimeout(),
+		skipInvalidFrames: cOpts.SkipInvalidFrames,
+		sampleTags:        tags,
+		samplesOutput:     c.vu.State().Samples,
+	}
+}
+
+// createLogger configures the log level (if specified) and returns the logger to be used by the client
+func createLogger(state *lib.State, cOpts *connectOptions) *logrus.Entry {
+	level, err := logrus.ParseLevel(cOpts.LogLevel)
+
+	if err == nil {
+		if logger, ok := state.Logger.(*logrus.Logger); ok {
+			logger.SetLevel(level)
+		}
+	}
+
+	return state.Logger.WithField("source", "cable")
+}
+
+// setOrigin sets the ORIGIN header from the cable URL unless it's been specified explicitly
+func setOrigin(headers http.Header, cableUrl string) {
+	if headers.Get("ORIGIN") != "" {
+		return
+	}
+
+	uri, err := url.Parse(cableUrl)
+	if err != nil {
+		return
+	}
+
+	var scheme string
+
+	if uri.Scheme == "wss" || uri.Scheme == "https" {
+		scheme = "https"
+	} else {
+		scheme = "http"
 	}
 
-	return &client, nil
+	headers.Set("ORIGIN", fmt.Sprintf("%s://%s", scheme, uri.Host))
 }
 
-func createDialer(state *lib.State, handshakeTimeout time.Duration) websocket.Dialer {
+func createDialer(state *lib.State, handshakeTimeout time.Duration, enableCompression bool) websocket.Dialer {
 	// Overriding the NextProtos to avoid talking http2
 	var tlsConfig *tls.Config
 	if state.TLSConfig != nil {
@@ -165,6 +267,23 @@ func createDialer(state *lib.State, handshakeTimeout time.Duration) websocket.Di
 		NetDialContext:  state.Dialer.DialContext,
 		Proxy:           http.ProxyFromEnvironment,
 		TLSClientConfig: tlsConfig,
+		// Negotiate permessage-deflate extension if requested
+		EnableCompression: enableCompression,
 	}
 	return wsd
 }
+
+// compressionNegotiated returns true if the server accepted the permessage-deflate extension
+func compressionNegotiated(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+
+	for _, ext := range resp.Header.Values("Sec-WebSocket-Extensions") {
+		if strings.Contains(ext, "permessage-deflate") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package cable
+
+import (
+	"sort"
+
+	"github.com/grafana/sobek"
+)
+
+// ParseCableReady returns the list of CableReady operations from the message (or null if it's not a CableReady message)
+func (c *Cable) ParseCableReady(msg sobek.Value) []interface{} {
+	if msg == nil || sobek.IsUndefined(msg) || sobek.IsNull(msg) {
+		return nil
+	}
+
+	ops, ok := cableReadyOperations(msg.Export())
+	if !ok {
+		return nil
+	}
+
+	results := make([]interface{}, len(ops))
+	for i, op := range ops {
+		results[i] = op
+	}
+
+	return results
+}
+
+// CableReady returns a matcher for CableReady messages to be used with receive functions
+// (e.g., `channel.receive(cable.cableReady({operation: "morph", selector: "#users"}))`)
+func (c *Cable) CableReady(cond sobek.Value) (*CableReadyMatcher, error) {
+	return newCableReadyMatcher(c.vu.Runtime(), cond)
+}
+
+// ReceiveOperations waits for a CableReady message with operations matching the condition
+// and returns the matching operations
+func (ch *Channel) ReceiveOperations(cond sobek.Value) []interface{} {
+	matcher, err := newCableReadyMatcher(ch.client.vu.Runtime(), cond)
+	if err != nil {
+		panic(err)
+	}
+
+	messages := ch.receiveN(1, matcher)
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ops, _ := cableReadyOperations(messages[0])
+
+	var results []interface{}
+
+	for _, op := range ops {
+		if matcher.matchOperation(op) {
+			results = append(results, op)
+		}
+	}
+
+	return results
+}
+
+// cableReadyOperations unpacks operations from the CableReady message.
+// Both the array format (CableReady 5+) and the object format (keyed by operation type) are supported.
+// The message is not modified; operations from the object format are ordered by operation type.
+func cableReadyOperations(msg interface{}) ([]map[string]interface{}, bool) {
+	payload, ok := msg.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	if isCableReady, _ := payload["cableReady"].(bool); !isCableReady {
+		return nil, false
+	}
+
+	var ops []map[string]interface{}
+
+	switch operations := payload["operations"].(type) {
+	case []interface{}:
+		for _, op := range operations {
+			if opObj, ok := op.(map[string]interface{}); ok {
+				ops = append(ops, opObj)
+			}
+		}
+	case map[string]interface{}:
+		opTypes := make([]string, 0, len(operations))
+		for opType := range operations {
+			opTypes = append(opTypes, opType)
+		}
+
+		sort.Strings(opTypes)
+
+		for _, opType := range opTypes {
+			items, ok := operations[opType].([]interface{})
+			if !ok {
+				continue
+			}
+
+			for _, op := range items {
+				opObj, ok := op.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				if _, ok := opObj["operation"]; !ok {
+					withType := make(map[string]interface{}, len(opObj)+1)
+					for k, v := range opObj {
+						withType[k] = v
+					}
+					withType["operation"] = opType
+					opObj = withType
+				}
+
+				ops = append(ops, opObj)
+			}
+		}
+	}
+
+	return ops, true
+}
+
+// CableReadyMatcher matches CableReady messages containing an operation which includes all the expected attributes
+// (e.g., `{operation: "morph", selector: "#users"}`). Attributes are matched the same way as by AttrMatcher.
+type CableReadyMatcher struct {
+	expected map[string]interface{}
+}
+
+func newCableReadyMatcher(rt *sobek.Runtime, cond sobek.Value) (*CableReadyMatcher, error) {
+	matcher := &CableReadyMatcher{}
+
+	if cond == nil || sobek.IsUndefined(cond) || sobek.IsNull(cond) {
+		return matcher, nil
+	}
+
+	expected, err := attrsFromValue(rt, cond)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAttrs(expected); err != nil {
+		return nil, err
+	}
+
+	matcher.expected = expected
+
+	return matcher, nil
+}
+
+func (m *CableReadyMatcher) Match(msg interface{}) bool {
+	ops, ok := cableReadyOperations(msg)
+	if !ok {
+		return false
+	}
+
+	for _, op := range ops {
+		if m.matchOperation(op) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *CableReadyMatcher) matchOperation(op map[string]interface{}) bool {
+	return matchAttrValue(m.expected, op, true)
+}
@@ -2,7 +2,6 @@ package cable
 
 import (
 	"encoding/json"
-	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -22,10 +21,20 @@ type Channel struct {
 	ackMu  sync.Mutex
 	readCh chan *cableMsg
 
+	// stash contains messages put back to the inbox (e.g., skipped while waiting for a response)
+	stash []*cableMsg
+
 	asyncHandlers []sobek.Callable
 
 	ignoreReads bool
 
+	// schema validates incoming messages (if configured)
+	schema *channelSchema
+	// sequence tracks ordering of incoming messages (if configured)
+	sequence *sequenceTracker
+	// delivery acknowledges messages registered in the delivery tracker (if configured)
+	delivery *deliveryReceiver
+
 	createdAt time.Time
 	ackedAt   time.Time
 }
@@ -45,6 +54,11 @@ func NewChannel(c *Client, identifier string) *Channel {
 func (ch *Channel) Perform(action string, attr sobek.Value) error {
 	rt := ch.client.vu.Runtime()
 	obj := attr.ToObject(rt).Export().(map[string]interface{})
+
+	return ch.perform(action, obj)
+}
+
+func (ch *Channel) perform(action string, obj map[string]interface{}) error {
 	obj["action"] = action
 	data, err := json.Marshal(obj)
 	if err != nil {
@@ -75,31 +89,36 @@ func (ch *Channel) Receive(attr sobek.Value) interface{} {
 
 // ReceiveN checks channels messages query for provided number of messages satisfying provided condition.
 func (ch *Channel) ReceiveN(n int, cond sobek.Value) []interface{} {
-	var results []interface{}
-	timeout := ch.client.recTimeout
-	timer := time.NewTimer(timeout)
 	matcher, err := ch.buildMatcher(cond)
 	if err != nil {
 		panic(err)
 	}
 
+	return ch.receiveN(n, matcher)
+}
+
+func (ch *Channel) receiveN(n int, matcher Matcher) []interface{} {
+	var results []interface{}
+	timeout := ch.client.recTimeout
+	timer := time.NewTimer(timeout)
+
 	i := 0
 	for {
-		select {
-		case msg := <-ch.readCh:
-			timer.Reset(timeout)
-			if !matcher.Match(msg.Message) {
-				continue
-			}
-			results = append(results, msg.Message)
-			i++
-			if i >= n {
-				return results
-			}
-		case <-timer.C:
+		msg := ch.next(timer.C)
+		if msg == nil {
 			ch.logger.Warn("receive timeout exceeded; consider increasing receiveTimeoutMs configuration option")
 			return results
 		}
+
+		timer.Reset(timeout)
+		if !matcher.Match(msg.Message) {
+			continue
+		}
+		results = append(results, msg.Message)
+		i++
+		if i >= n {
+			return results
+		}
 	}
 }
 
@@ -114,16 +133,41 @@ func (ch *Channel) ReceiveAll(sec int, cond sobek.Value) []interface{} {
 	}
 
 	for {
-		select {
-		case msg := <-ch.readCh:
-			if !matcher.Match(msg.Message) {
-				continue
-			}
-			results = append(results, msg.Message)
-		case <-timer.C:
+		msg := ch.next(timer.C)
+		if msg == nil {
 			return results
 		}
+
+		if !matcher.Match(msg.Message) {
+			continue
+		}
+		results = append(results, msg.Message)
+	}
+}
+
+// next returns the next incoming message (stashed messages go first) or nil if the timeout channel fires first
+func (ch *Channel) next(timeout <-chan time.Time) *cableMsg {
+	if len(ch.stash) > 0 {
+		msg := ch.stash[0]
+		ch.stash = ch.stash[1:]
+		return msg
+	}
+
+	select {
+	case msg := <-ch.readCh:
+		return msg
+	case <-timeout:
+		return nil
+	}
+}
+
+// unshift puts messages back to the inbox, so they're returned first by the subsequent receive calls
+func (ch *Channel) unshift(msgs []*cableMsg) {
+	if len(msgs) == 0 {
+		return
 	}
+
+	ch.stash = append(msgs, ch.stash...)
 }
 
 // Register callback to receive messages asynchronously
@@ -152,7 +196,25 @@ func (ch *Channel) handleAck(val bool, when time.Time) {
 	ch.confCh <- val
 }
 
+// closed flushes the channel metrics and stops delivery tracking once the connection is closed
+func (ch *Channel) closed() {
+	ch.flushSequenceMissing()
+	ch.releaseDelivery()
+}
+
 func (ch *Channel) handleIncoming(msg *cableMsg) {
+	if ch.schema != nil {
+		ch.validateSchema(msg)
+	}
+
+	if ch.sequence != nil {
+		ch.trackSequence(msg)
+	}
+
+	if ch.delivery != nil {
+		ch.trackDelivery(msg)
+	}
+
 	ch.handleAsync(msg)
 
 	if ch.ignoreReads {
@@ -216,25 +278,6 @@ func (m *StringMatcher) Match(msg interface{}) bool {
 	return m.expected == msgStr
 }
 
-type AttrMatcher struct {
-	expected map[string]interface{}
-}
-
-func (m *AttrMatcher) Match(msg interface{}) bool {
-	msgObj, ok := msg.(map[string]interface{})
-	if !ok {
-		return false
-	}
-
-	for k, v := range m.expected {
-		if !reflect.DeepEqual(v, msgObj[k]) {
-			return false
-		}
-	}
-
-	return true
-}
-
 type PassthruMatcher struct{}
 
 func (PassthruMatcher) Match(_ interface{}) bool {
@@ -245,7 +288,8 @@ func (PassthruMatcher) Match(_ interface{}) bool {
 // - when condition is nil, match is always successful
 // - when condition is a func, result of func(msg) is used as a result of match
 // - when condition is a string, match is successful when message matches provided string
-// - when condition is an object, match is successful when message includes all object attributes
+// - when condition is a matcher built via a dedicated constructor (e.g., cable.turbo(), cable.cableReady() or cable.where()), the matcher is used as is
+// - when condition is an object, match is successful when message includes all object attributes (see AttrMatcher)
 func (ch *Channel) buildMatcher(cond sobek.Value) (Matcher, error) {
 	if cond == nil || sobek.IsUndefined(cond) || sobek.IsNull(cond) {
 		return &PassthruMatcher{}, nil
@@ -261,14 +305,29 @@ func (ch *Channel) buildMatcher(cond sobek.Value) (Matcher, error) {
 		return &FuncMatcher{ch.client.vu, userFunc}, nil
 	}
 
+	// Matchers built via dedicated constructors (e.g., cable.turbo(...))
+	if m, ok := cond.Export().(Matcher); ok {
+		return m, nil
+	}
+
+	matcher, err := attrsFromValue(ch.client.vu.Runtime(), cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAttrMatcher(matcher)
+}
+
+// attrsFromValue converts JS object into a map
+func attrsFromValue(rt *sobek.Runtime, cond sobek.Value) (map[string]interface{}, error) {
 	// we need to pass object through json unmarshalling to use same types for numbers
-	jsonAttr, err := cond.ToObject(ch.client.vu.Runtime()).MarshalJSON()
+	jsonAttr, err := cond.ToObject(rt).MarshalJSON()
 	if err != nil {
 		return nil, err
 	}
 
-	var matcher map[string]interface{}
-	_ = json.Unmarshal(jsonAttr, &matcher)
+	var attrs map[string]interface{}
+	_ = json.Unmarshal(jsonAttr, &attrs)
 
-	return &AttrMatcher{matcher}, nil
+	return attrs, nil
 }
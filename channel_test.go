@@ -0,0 +1,133 @@
+package cable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+func TestChannelReceiveMatchers(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { n: 1, kind: "a" });
+		channel.perform("echo", { n: 2, kind: "b" });
+		channel.perform("echo", { n: 3, kind: "a" });
+		channel.perform("echo", { n: 4, kind: "b" });
+
+		const byAttr = channel.receive({ kind: "b" });
+		const byFunc = channel.receive((msg) => msg.n > 3);
+
+		[byAttr.n, byFunc.n]
+	`).Export().([]interface{})
+
+	assert.EqualValues(t, 2, val[0])
+	assert.EqualValues(t, 4, val[1])
+}
+
+func TestChannelReceiveN(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL, { receiveTimeoutMs: 200 });
+		const channel = client.subscribe("EchoChannel");
+
+		for (let i = 0; i < 5; i++) {
+			channel.perform("echo", { n: i });
+		}
+
+		const three = channel.receiveN(3);
+		// Only two messages left, so the timeout must be hit
+		const rest = channel.receiveN(3);
+
+		[three.map((m) => m.n), rest.map((m) => m.n)]
+	`).Export().([]interface{})
+
+	assert.EqualValues(t, []interface{}{int64(0), int64(1), int64(2)}, val[0])
+	assert.EqualValues(t, []interface{}{int64(3), int64(4)}, val[1])
+}
+
+func TestChannelReceiveAll(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, mockserver.Config{
+		Channels: map[string]*mockserver.Channel{
+			"NewsChannel": {
+				Broadcasts: []mockserver.Broadcast{
+					{Delay: 10 * time.Millisecond, Message: map[string]interface{}{"title": "first"}},
+					{Delay: 20 * time.Millisecond, Message: map[string]interface{}{"title": "second"}},
+					{Delay: 30 * time.Millisecond, Message: map[string]interface{}{"title": "third"}},
+				},
+			},
+		},
+	})
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("NewsChannel");
+
+		channel.receiveAll(1, (msg) => msg.title !== "second").map((msg) => msg.title)
+	`).Export()
+
+	assert.Equal(t, []interface{}{"first", "third"}, val)
+}
+
+func TestChannelBroadcastToOthers(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const alice = cable.connect(CABLE_URL).subscribe("EchoChannel", { room: 1 });
+		const bob = cable.connect(CABLE_URL).subscribe("EchoChannel", { room: 1 });
+
+		alice.perform("broadcast", { text: "hi all" });
+
+		[alice.receive().text, bob.receive().text]
+	`).Export()
+
+	assert.Equal(t, []interface{}{"hi all", "hi all"}, val)
+	assert.Equal(t, 2, server.ConnectionsCount())
+}
+
+func TestChannelIgnoreReads(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL, { receiveTimeoutMs: 100 });
+		const channel = client.subscribe("EchoChannel");
+		channel.ignoreReads();
+
+		channel.perform("echo", { n: 1 });
+		channel.receive()
+	`)
+
+	assert.Nil(t, val.Export())
+}
+
+func TestChannelRequest(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { n: 1 });
+		const response = channel.request("echo", { n: 2 });
+
+		// The message received while waiting for the response must be kept in the inbox
+		[response.n, typeof response.request_id, channel.receive().n]
+	`).Export().([]interface{})
+
+	assert.EqualValues(t, 2, val[0])
+	assert.Equal(t, "string", val[1])
+	assert.EqualValues(t, 1, val[2])
+}
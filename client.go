@@ -2,9 +2,11 @@ package cable
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.k6.io/k6/js/modules"
@@ -21,33 +23,74 @@ type cableMsg struct {
 	Identifier string      `json:"identifier,omitempty"`
 	Data       string      `json:"data,omitempty"`
 	Message    interface{} `json:"message,omitempty"`
+	SID        string      `json:"sid,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	Reconnect  *bool       `json:"reconnect,omitempty"`
 
 	receivedAt time.Time
 }
 
+const (
+	// closeReasonDisconnect is used when the server sent the disconnect message
+	closeReasonDisconnect = "disconnect"
+	// closeReasonDrop is used when the connection has been closed without the disconnect message
+	closeReasonDrop = "drop"
+
+	// closeSamplesTimeout is the max time to wait for metrics emitted on close to be consumed
+	closeSamplesTimeout = time.Second
+)
+
 type Client struct {
-	vu       modules.VU
-	codec    *Codec
-	conn     *websocket.Conn
-	channels map[string]*Channel
+	vu        modules.VU
+	transport transport
+	channels  map[string]*Channel
 
 	readCh  chan *cableMsg
 	errorCh chan error
 	closeCh chan int
 
+	// raw captures incoming frames for the raw frames API
+	raw *rawTap
+
+	// closedCh is closed when the receive loop exits; closeReason must only be read after that
+	closedCh    chan struct{}
+	closeReason string
+
 	disconnected bool
 
+	// sid is the session (socket) ID provided by the server in the welcome message (if any)
+	sid string
+
+	// implicitIdentifier is the identifier of the channel subscribed on connect (e.g., via SSE URL params)
+	implicitIdentifier string
+
+	// compressed is true when permessage-deflate has been negotiated
+	compressed bool
+	netConn    *meteredConn
+	// bytesReceived is the total size of the received (decompressed) messages
+	bytesReceived int64
+
+	// channelNames caches channel names extracted from identifiers (used for metrics tags)
+	channelNames sync.Map
+
 	mu         sync.Mutex
 	logger     *logrus.Entry
 	recTimeout time.Duration
 
+	// skipInvalidFrames makes the client skip incoming frames that couldn't be decoded
+	// (by default, such frames close the connection)
+	skipInvalidFrames bool
+
+	metrics       *cableMetrics
+	trackers      *trackerRegistry
+	scriptDir     string
 	sampleTags    *metrics.TagSet
 	samplesOutput chan<- metrics.SampleContainer
 }
 
 // Subscribe creates and returns Channel
-func (c *Client) Subscribe(channelName string, paramsIn sobek.Value) (*Channel, error) {
-	promise, err := c.SubscribeAsync(channelName, paramsIn)
+func (c *Client) Subscribe(channelName string, paramsIn sobek.Value, optsIn sobek.Value) (*Channel, error) {
+	promise, err := c.SubscribeAsync(channelName, paramsIn, optsIn)
 	if err != nil {
 		return nil, err
 	}
@@ -86,15 +129,21 @@ func (sp *SubscribePromise) Await(ms int) (*Channel, error) {
 }
 
 // Subscribe creates and returns Channel
-func (c *Client) SubscribeAsync(channelName string, paramsIn sobek.Value) (*SubscribePromise, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
+func (c *Client) SubscribeAsync(channelName string, paramsIn sobek.Value, optsIn sobek.Value) (*SubscribePromise, error) {
 	params, err := c.parseParams(paramsIn)
 	if err != nil {
 		return nil, err
 	}
 
+	opts, err := parseSubscribeOptions(c.vu.Runtime(), optsIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.subscribe(channelName, params, opts)
+}
+
+func (c *Client) subscribe(channelName string, params map[string]interface{}, opts *subscribeOptions) (*SubscribePromise, error) {
 	params["channel"] = channelName
 
 	identifierJSON, err := json.Marshal(params)
@@ -102,16 +151,31 @@ func (c *Client) SubscribeAsync(channelName string, paramsIn sobek.Value) (*Subs
 		return nil, err
 	}
 
-	identifier := string(identifierJSON)
+	return c.subscribeIdentifier(string(identifierJSON), channelName, opts)
+}
+
+// subscribeIdentifier subscribes to the channel using the identifier as is
+func (c *Client) subscribeIdentifier(identifier string, channelName string, opts *subscribeOptions) (*SubscribePromise, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	if c.channels[identifier] != nil {
+		if identifier == c.implicitIdentifier {
+			return &SubscribePromise{client: c, channel: c.channels[identifier]}, nil
+		}
+
 		c.logger.Warnf("already subscribed to `%v` channel\n", channelName)
 		return &SubscribePromise{client: c, channel: c.channels[identifier]}, nil
 	}
 
 	channel := NewChannel(c, identifier)
 
+	if err := channel.configure(opts); err != nil {
+		return nil, err
+	}
+
 	if err := c.send(&cableMsg{Command: "subscribe", Identifier: identifier}); err != nil {
+		channel.releaseDelivery()
 		return nil, err
 	}
 
@@ -120,6 +184,16 @@ func (c *Client) SubscribeAsync(channelName string, paramsIn sobek.Value) (*Subs
 	return &SubscribePromise{client: c, channel: channel}, nil
 }
 
+// SessionID returns the session ID provided by the server on connect (AnyCable sid or Pusher socket_id)
+func (c *Client) SessionID() string {
+	return c.sid
+}
+
+// CompressionEnabled returns true if the server accepted permessage-deflate compression
+func (c *Client) CompressionEnabled() bool {
+	return c.compressed
+}
+
 func (c *Client) Disconnect() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -129,7 +203,26 @@ func (c *Client) Disconnect() {
 	}
 
 	c.disconnected = true
-	_ = c.conn.Close()
+
+	for _, channel := range c.channels {
+		channel.closed()
+	}
+
+	_ = c.transport.Close()
+}
+
+// flushChannels notifies the channels that the connection is closed
+func (c *Client) flushChannels() {
+	c.mu.Lock()
+	channels := make([]*Channel, 0, len(c.channels))
+	for _, channel := range c.channels {
+		channels = append(channels, channel)
+	}
+	c.mu.Unlock()
+
+	for _, channel := range channels {
+		channel.closed()
+	}
 }
 
 // Repeat function in a loop until it returns false
@@ -174,19 +267,139 @@ func (c *Client) send(msg *cableMsg) error {
 		return errCableInInitContext
 	}
 
-	err := c.codec.Send(c.conn, msg)
+	var wireBefore int64
+	if c.compressed {
+		wireBefore = c.netConn.bytesWritten()
+	}
+
+	size, err := c.transport.Send(msg)
+	now := time.Now()
+
 	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
 		TimeSeries: metrics.TimeSeries{
 			Metric: state.BuiltinMetrics.WSMessagesSent,
 			Tags:   c.sampleTags,
 		},
-		Time:  time.Now(),
+		Time:  now,
 		Value: 1,
 	})
 
+	if err == nil {
+		c.trackMessageSize(c.metrics.MessageSizeSent, msg.Identifier, msg.Command, size, now)
+	}
+
+	if c.compressed && err == nil {
+		c.trackCompression(c.metrics.CompressedBytesSent, c.metrics.UncompressedBytesSent, c.netConn.bytesWritten()-wireBefore, size, now)
+	}
+
 	return err
 }
 
+// trackMessageSize pushes the message payload size tagged with the channel name and the message type
+func (c *Client) trackMessageSize(metric *metrics.Metric, identifier string, msgType string, size int, when time.Time) {
+	tags := c.sampleTags
+
+	if name := c.channelName(identifier); name != "" {
+		tags = tags.With("channel", name)
+	}
+
+	if msgType == "" {
+		msgType = "message"
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   tags.With("type", msgType),
+		},
+		Time:  when,
+		Value: float64(size),
+	})
+}
+
+// channelName extracts the channel name from the identifier (results are cached)
+func (c *Client) channelName(identifier string) string {
+	if identifier == "" {
+		return ""
+	}
+
+	if name, ok := c.channelNames.Load(identifier); ok {
+		return name.(string)
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(identifier), &params); err != nil {
+		return ""
+	}
+
+	name, _ := params["channel"].(string)
+	c.channelNames.Store(identifier, name)
+
+	return name
+}
+
+// trackCompression pushes compressed (on the wire) and uncompressed message sizes
+func (c *Client) trackCompression(compressedMetric, uncompressedMetric *metrics.Metric, compressed int64, uncompressed int, when time.Time) {
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Samples{
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: compressedMetric,
+				Tags:   c.sampleTags,
+			},
+			Time:  when,
+			Value: float64(compressed),
+		},
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: uncompressedMetric,
+				Tags:   c.sampleTags,
+			},
+			Time:  when,
+			Value: float64(uncompressed),
+		},
+	})
+}
+
+// trackReceivedCompression increments the compressed (on the wire) and uncompressed received data counters by the connection totals.
+// Incoming frames are read via a buffered reader, so the wire size can only be measured per connection.
+func (c *Client) trackReceivedCompression() {
+	if !c.compressed {
+		return
+	}
+
+	now := time.Now()
+
+	c.pushOnClose(metrics.Samples{
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: c.metrics.CompressedDataReceived,
+				Tags:   c.sampleTags,
+			},
+			Time:  now,
+			Value: float64(c.netConn.frameBytesRead()),
+		},
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: c.metrics.UncompressedDataReceived,
+				Tags:   c.sampleTags,
+			},
+			Time:  now,
+			Value: float64(atomic.LoadInt64(&c.bytesReceived)),
+		},
+	})
+}
+
+// pushOnClose pushes samples emitted when the connection is closed.
+// The connection could be closed because the VU context is done, so samples are sent directly
+// (the output is still consumed at this point).
+func (c *Client) pushOnClose(samples metrics.SampleContainer) {
+	select {
+	case c.samplesOutput <- samples:
+	case <-time.After(closeSamplesTimeout):
+		c.logger.Debugln("failed to push connection metrics: timeout")
+	}
+}
+
 // start waits for the welcome message and then starts receive and handle loops.
 func (c *Client) start() error {
 	err := c.receiveWelcomeMsg()
@@ -194,24 +407,32 @@ func (c *Client) start() error {
 		return err
 	}
 
-	go c.handleLoop()
-	go c.receiveLoop()
+	c.startLoops()
 
 	return nil
 }
 
+func (c *Client) startLoops() {
+	go c.handleLoop()
+	go c.receiveLoop()
+}
+
 func (c *Client) handleLoop() {
 	for {
 		select {
 		case msg := <-c.readCh:
-			if c.channels[msg.Identifier] != nil {
+			c.mu.Lock()
+			channel := c.channels[msg.Identifier]
+			c.mu.Unlock()
+
+			if channel != nil {
 				switch msg.Type {
 				case "confirm_subscription":
-					c.channels[msg.Identifier].handleAck(true, msg.receivedAt)
+					channel.handleAck(true, msg.receivedAt)
 				case "reject_subscription":
-					c.channels[msg.Identifier].handleAck(false, msg.receivedAt)
+					channel.handleAck(false, msg.receivedAt)
 				default:
-					c.channels[msg.Identifier].handleIncoming(msg)
+					channel.handleIncoming(msg)
 				}
 			}
 		case err := <-c.errorCh:
@@ -219,7 +440,8 @@ func (c *Client) handleLoop() {
 			continue
 		case <-c.closeCh:
 		case <-c.vu.Context().Done():
-			_ = c.conn.Close()
+			c.flushChannels()
+			_ = c.transport.Close()
 			c.logger.Debugln("connection closed")
 			return
 		}
@@ -227,9 +449,13 @@ func (c *Client) handleLoop() {
 }
 
 func (c *Client) receiveLoop() {
+	defer close(c.closedCh)
+	defer c.trackReceivedCompression()
+
 	for {
 		obj, err := c.receiveIgnoringPing()
 		if err != nil {
+			c.closeReason = closeReasonDrop
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
 				select {
 				case c.errorCh <- err:
@@ -248,6 +474,7 @@ func (c *Client) receiveLoop() {
 
 		if obj.Type == "disconnect" {
 			c.logger.Debugln("connection closed by server")
+			c.closeReason = closeReasonDisconnect
 			c.Disconnect()
 			return
 		}
@@ -281,17 +508,34 @@ func (c *Client) receiveWelcomeMsg() error {
 		return fmt.Errorf("expected welcome msg, got %v", obj)
 	}
 
+	c.sid = obj.SID
+
 	return nil
 }
 
 func (c *Client) receiveIgnoringPing() (*cableMsg, error) {
 	for {
 		var msg cableMsg
-		if err := c.codec.Receive(c.conn, &msg); err != nil {
+
+		size, err := c.transport.Receive(&msg)
+		if err != nil {
+			var derr *decodeError
+			if c.skipInvalidFrames && errors.As(err, &derr) {
+				c.logger.Warnf("%v", err)
+				c.trackMessageSize(c.metrics.MessageSizeReceived, "", "invalid", size, time.Now())
+				continue
+			}
 			return nil, err
 		}
 		c.logger.Debugf("message received: `%#v`\n", msg)
 
+		now := time.Now()
+		c.trackMessageSize(c.metrics.MessageSizeReceived, msg.Identifier, msg.Type, size, now)
+
+		if c.compressed {
+			atomic.AddInt64(&c.bytesReceived, int64(size))
+		}
+
 		if msg.Type == "ping" {
 			continue
 		}
@@ -301,6 +545,7 @@ func (c *Client) receiveIgnoringPing() (*cableMsg, error) {
 		timestamp := int64(time.Now().UnixNano()) / 1_000_000
 
 		if data, ok := msg.Message.(map[string]interface{}); ok {
+			c.trackBroadcastLatency(&msg, data)
 			data["__timestamp__"] = timestamp
 			msg.Message = data
 		}
@@ -0,0 +1,235 @@
+package cable
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+func TestConnectWithCodecs(t *testing.T) {
+	for _, codec := range []string{"json", "msgpack", "protobuf"} {
+		codec := codec
+
+		t.Run(codec, func(t *testing.T) {
+			ts := newTestState(t)
+			ts.startMockServer(t, echoServerConfig())
+
+			val := ts.run(t, `
+				const client = cable.connect(CABLE_URL, { codec: "`+codec+`" });
+				const channel = client.subscribe("EchoChannel", { room: 42 });
+
+				channel.perform("echo", { text: "hello", n: 1 });
+				const msg = channel.receive();
+
+				client.disconnect();
+
+				[client.sessionID(), msg.text, msg.n, msg.action]
+			`).Export().([]interface{})
+
+			// Protobuf schema doesn't include session IDs
+			if codec != "protobuf" {
+				assert.NotEmpty(t, val[0])
+			}
+
+			assert.Equal(t, "hello", val[1])
+			assert.EqualValues(t, 1, val[2])
+			assert.Equal(t, "echo", val[3])
+		})
+	}
+}
+
+func TestConnectFailure(t *testing.T) {
+	ts := newTestState(t)
+
+	val := ts.run(t, `cable.connect("ws://127.0.0.1:1/cable", { handshakeTimeoutS: 1 })`)
+
+	assert.True(t, val == nil || val.Export() == nil)
+}
+
+func TestConnectUnauthorized(t *testing.T) {
+	ts := newTestState(t)
+	config := echoServerConfig()
+	config.Authenticate = func(r *http.Request) bool {
+		_, err := r.Cookie("user_id")
+		return err == nil
+	}
+	ts.startMockServer(t, config)
+
+	val := ts.run(t, `cable.connect(CABLE_URL)`)
+	assert.Nil(t, val.Export())
+
+	val = ts.run(t, `cable.connect(CABLE_URL, { cookies: "user_id=42" }).sessionID()`)
+	assert.NotEmpty(t, val.String())
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	_, err := ts.VU.Runtime().RunString(`
+		const client = cable.connect(CABLE_URL);
+		client.subscribe("RejectChannel");
+	`)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rejected")
+}
+
+func TestSubscribeTimeout(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, mockserver.Config{
+		Channels: map[string]*mockserver.Channel{
+			"SlowChannel": {ConfirmDelay: 500 * time.Millisecond},
+		},
+	})
+
+	_, err := ts.VU.Runtime().RunString(`
+		const client = cable.connect(CABLE_URL, { receiveTimeoutMs: 100 });
+		client.subscribe("SlowChannel");
+	`)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout exceeded")
+}
+
+func TestSubscribeAsync(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const first = client.subscribeAsync("EchoChannel", { id: 1 });
+		const second = client.subscribeAsync("EchoChannel", { id: 2 });
+
+		[first.await(), second.await()].every((ch) => ch.ackDuration() >= 0)
+	`)
+
+	assert.True(t, val.ToBoolean())
+}
+
+func TestServerDisconnect(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		client.subscribe("EchoChannel");
+	`)
+
+	server.Disconnect("server_restart", true)
+
+	client := ts.VU.Runtime().Get("client").Export().(*Client)
+
+	select {
+	case <-client.closedCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection hasn't been closed")
+	}
+
+	assert.Equal(t, closeReasonDisconnect, client.closeReason)
+}
+
+func TestCompression(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL, { compression: "deflate" });
+		const channel = client.subscribe("EchoChannel");
+		channel.perform("echo", { text: "a".repeat(1024) });
+
+		[client.compressionEnabled(), channel.receive().text.length]
+	`).Export().([]interface{})
+
+	assert.Equal(t, true, val[0])
+	assert.EqualValues(t, 1024, val[1])
+}
+
+// startInvalidFramesServer starts a server responding to subscribe commands with a confirmation,
+// a malformed frame and a valid message
+func startInvalidFramesServer(t *testing.T) string {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"welcome"}`)) != nil {
+			return
+		}
+
+		for {
+			var cmd cableMsg
+			if conn.ReadJSON(&cmd) != nil {
+				return
+			}
+
+			if cmd.Command != "subscribe" {
+				continue
+			}
+
+			msg, _ := json.Marshal(map[string]interface{}{"identifier": cmd.Identifier, "message": map[string]string{"text": "after"}})
+
+			_ = conn.WriteJSON(map[string]string{"type": "confirm_subscription", "identifier": cmd.Identifier})
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":`))
+			_ = conn.WriteMessage(websocket.TextMessage, msg)
+		}
+	}))
+
+	t.Cleanup(func() {
+		server.CloseClientConnections()
+		server.Close()
+	})
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestInvalidFrames(t *testing.T) {
+	ts := newTestState(t)
+	require.NoError(t, ts.VU.Runtime().Set("INVALID_URL", startInvalidFramesServer(t)))
+
+	val := ts.run(t, `
+		const client = cable.connect(INVALID_URL, { receiveTimeoutMs: 200 });
+		const channel = client.subscribe("ChatChannel");
+
+		channel.receive()
+	`)
+
+	assert.True(t, val == nil || sobek.IsUndefined(val) || sobek.IsNull(val))
+
+	// Frames that cannot be decoded close the connection
+	client := ts.VU.Runtime().Get("client").Export().(*Client)
+
+	select {
+	case <-client.closedCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection hasn't been closed")
+	}
+
+	assert.Equal(t, closeReasonDrop, client.closeReason)
+
+	val = ts.run(t, `
+		const skipping = cable.connect(INVALID_URL, { skipInvalidFrames: true });
+		skipping.subscribe("ChatChannel").receive().text
+	`)
+
+	assert.Equal(t, "after", val.String())
+
+	invalid := ts.requireMetric(t, "cable_message_size_received", map[string]string{"type": "invalid"})
+	assert.Len(t, invalid, 1)
+}
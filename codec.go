@@ -1,8 +1,9 @@
 package cable
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/websocket"
@@ -11,62 +12,91 @@ import (
 	pb "github.com/anycable/xk6-cable/ac_protos"
 )
 
+// Codec encodes and decodes cable messages to and from WebSocket frames payloads
 type Codec struct {
-	Receive func(*websocket.Conn, interface{}) error
-	Send    func(*websocket.Conn, interface{}) error
+	// MessageType is the type of WebSocket frames used to send messages
+	MessageType int
+	// BinaryOnly makes the codec reject incoming text frames
+	BinaryOnly bool
+	Encode     func(interface{}) ([]byte, error)
+	Decode     func([]byte, interface{}) error
+}
+
+// Receive reads the next frame from the connection and decodes it.
+// Returns the size of the message payload in bytes.
+func (c *Codec) Receive(conn *websocket.Conn, v interface{}) (int, error) {
+	mtype, raw, err := conn.ReadMessage()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(raw), c.DecodeFrame(mtype, raw, v)
+}
+
+// DecodeFrame checks the frame type and decodes the frame payload
+func (c *Codec) DecodeFrame(mtype int, raw []byte, v interface{}) error {
+	if c.BinaryOnly && mtype != websocket.BinaryMessage {
+		return fmt.Errorf("Unexpected message type: %v", mtype)
+	}
+
+	return c.Decode(raw, v)
+}
+
+// Send encodes the message and writes it to the connection.
+// Returns the size of the message payload in bytes.
+func (c *Codec) Send(conn *websocket.Conn, v interface{}) (int, error) {
+	b, err := c.Encode(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), conn.WriteMessage(c.MessageType, b)
 }
 
 var JSONCodec = &Codec{
-	Receive: func(c *websocket.Conn, v interface{}) error {
-		return c.ReadJSON(v)
+	MessageType: websocket.TextMessage,
+	Encode: func(v interface{}) ([]byte, error) {
+		return json.Marshal(v)
 	},
-	Send: func(c *websocket.Conn, v interface{}) error {
-		return c.WriteJSON(v)
+	Decode: func(raw []byte, v interface{}) error {
+		return json.Unmarshal(raw, v)
 	},
 }
 
 var MsgPackCodec = &Codec{
-	Receive: func(c *websocket.Conn, v interface{}) error {
-		_, r, err := c.NextReader()
-		if err != nil {
-			return err
-		}
-		enc := msgpack.NewDecoder(r)
+	MessageType: websocket.BinaryMessage,
+	Encode: func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
 		enc.SetCustomStructTag("json")
-		return enc.Decode(v)
-	},
-	Send: func(c *websocket.Conn, v interface{}) error {
-		w, err := c.NextWriter(websocket.BinaryMessage)
-		if err != nil {
-			return err
-		}
-		enc := msgpack.NewEncoder(w)
-		enc.SetCustomStructTag("json")
-		err1 := enc.Encode(v)
-		err2 := w.Close()
-		if err1 != nil {
-			return err1
+		if err := enc.Encode(v); err != nil {
+			return nil, err
 		}
-		return err2
+
+		return buf.Bytes(), nil
+	},
+	Decode: func(raw []byte, v interface{}) error {
+		dec := msgpack.NewDecoder(bytes.NewReader(raw))
+		dec.SetCustomStructTag("json")
+		return dec.Decode(v)
 	},
 }
 
 var ProtobufCodec = &Codec{
-	Receive: func(c *websocket.Conn, v interface{}) error {
-		mtype, r, err := c.NextReader()
-		if err != nil {
-			return err
-		}
+	MessageType: websocket.BinaryMessage,
+	BinaryOnly:  true,
+	Encode: func(v interface{}) ([]byte, error) {
+		msg := (v).(*cableMsg)
 
-		if mtype != websocket.BinaryMessage {
-			return fmt.Errorf("Unexpected message type: %v", mtype)
-		}
+		buf := &pb.Message{}
 
-		raw, err := ioutil.ReadAll(r)
-		if err != nil {
-			return err
-		}
+		buf.Command = pb.Command(pb.Command_value[msg.Command])
+		buf.Identifier = msg.Identifier
+		buf.Data = msg.Data
 
+		return proto.Marshal(buf)
+	},
+	Decode: func(raw []byte, v interface{}) error {
 		buf := &pb.Message{}
 		if err := proto.Unmarshal(raw, buf); err != nil {
 			return err
@@ -76,37 +106,20 @@ var ProtobufCodec = &Codec{
 
 		msg.Type = buf.Type.String()
 		msg.Identifier = buf.Identifier
+		msg.Reason = buf.Reason
+
+		if buf.Type == pb.Type_disconnect {
+			reconnect := buf.Reconnect
+			msg.Reconnect = &reconnect
+		}
 
 		if buf.Message != nil {
 			var message interface{}
 
-			err = msgpack.Unmarshal(buf.Message, &message)
+			_ = msgpack.Unmarshal(buf.Message, &message)
 			msg.Message = message
 		}
 
 		return nil
 	},
-	Send: func(c *websocket.Conn, v interface{}) error {
-		msg := (v).(*cableMsg)
-
-		buf := &pb.Message{}
-
-		buf.Command = pb.Command(pb.Command_value[msg.Command])
-		buf.Identifier = msg.Identifier
-		buf.Data = msg.Data
-
-		b, err := proto.Marshal(buf)
-		if err != nil {
-			return err
-		}
-
-		w, err := c.NextWriter(websocket.BinaryMessage)
-		if err != nil {
-			return err
-		}
-
-		w.Write(b)
-		err = w.Close()
-		return err
-	},
 }
@@ -0,0 +1,188 @@
+package cable
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+
+	pb "github.com/anycable/xk6-cable/ac_protos"
+)
+
+func TestJSONCodec(t *testing.T) {
+	assert.Equal(t, websocket.TextMessage, JSONCodec.MessageType)
+
+	b, err := JSONCodec.Encode(&cableMsg{Command: "subscribe", Identifier: `{"channel":"EchoChannel"}`})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"command":"subscribe","identifier":"{\"channel\":\"EchoChannel\"}"}`, string(b))
+
+	var msg cableMsg
+	require.NoError(t, JSONCodec.Decode([]byte(`{"type":"disconnect","reason":"unauthorized","reconnect":false}`), &msg))
+	assert.Equal(t, "disconnect", msg.Type)
+	assert.Equal(t, "unauthorized", msg.Reason)
+	require.NotNil(t, msg.Reconnect)
+	assert.False(t, *msg.Reconnect)
+}
+
+func TestMsgPackCodec(t *testing.T) {
+	assert.Equal(t, websocket.BinaryMessage, MsgPackCodec.MessageType)
+
+	b, err := MsgPackCodec.Encode(&cableMsg{Command: "message", Identifier: "id", Data: `{"action":"echo"}`})
+	require.NoError(t, err)
+
+	// Struct fields must be encoded using the json tags
+	var encoded map[string]interface{}
+	require.NoError(t, msgpack.Unmarshal(b, &encoded))
+	assert.Equal(t, map[string]interface{}{"command": "message", "identifier": "id", "data": `{"action":"echo"}`}, encoded)
+
+	raw, err := msgpack.Marshal(map[string]interface{}{"type": "welcome", "sid": "abc"})
+	require.NoError(t, err)
+
+	var msg cableMsg
+	require.NoError(t, MsgPackCodec.Decode(raw, &msg))
+	assert.Equal(t, "welcome", msg.Type)
+	assert.Equal(t, "abc", msg.SID)
+}
+
+func TestProtobufCodec(t *testing.T) {
+	assert.Equal(t, websocket.BinaryMessage, ProtobufCodec.MessageType)
+
+	b, err := ProtobufCodec.Encode(&cableMsg{Command: "subscribe", Identifier: "id", Data: "data"})
+	require.NoError(t, err)
+
+	var encoded pb.Message
+	require.NoError(t, proto.Unmarshal(b, &encoded))
+	assert.Equal(t, pb.Command_subscribe, encoded.Command)
+	assert.Equal(t, "id", encoded.Identifier)
+	assert.Equal(t, "data", encoded.Data)
+
+	payload, err := msgpack.Marshal(map[string]interface{}{"text": "hello"})
+	require.NoError(t, err)
+
+	raw, err := proto.Marshal(&pb.Message{Type: pb.Type_confirm_subscription, Identifier: "id", Message: payload})
+	require.NoError(t, err)
+
+	var msg cableMsg
+	require.NoError(t, ProtobufCodec.Decode(raw, &msg))
+	assert.Equal(t, "confirm_subscription", msg.Type)
+	assert.Equal(t, "id", msg.Identifier)
+	assert.Equal(t, map[string]interface{}{"text": "hello"}, msg.Message)
+	assert.Nil(t, msg.Reconnect)
+
+	raw, err = proto.Marshal(&pb.Message{Type: pb.Type_disconnect, Reason: "server_restart", Reconnect: true})
+	require.NoError(t, err)
+
+	msg = cableMsg{}
+	require.NoError(t, ProtobufCodec.Decode(raw, &msg))
+	assert.Equal(t, "server_restart", msg.Reason)
+	require.NotNil(t, msg.Reconnect)
+	assert.True(t, *msg.Reconnect)
+}
+
+func TestCodecDecodeError(t *testing.T) {
+	for name, codec := range map[string]*Codec{"json": JSONCodec, "msgpack": MsgPackCodec, "protobuf": ProtobufCodec} {
+		var msg cableMsg
+		assert.Error(t, codec.Decode([]byte{0xc1, 0xff, 0x00}, &msg), name)
+	}
+
+	var msg cableMsg
+	assert.Error(t, JSONCodec.Decode(json.RawMessage(`{"type":`), &msg))
+}
+
+// wsPair returns both ends of a WebSocket connection
+func wsPair(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	serverConns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		serverConns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+
+	conn := <-serverConns
+
+	t.Cleanup(func() {
+		client.Close()
+		conn.Close()
+	})
+
+	return client, conn
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	reconnect := false
+
+	for name, codec := range map[string]*Codec{"json": JSONCodec, "msgpack": MsgPackCodec} {
+		client, server := wsPair(t)
+
+		sent := &cableMsg{Type: "disconnect", Identifier: "id", Message: map[string]interface{}{"text": "hello"}, Reason: "server_restart", Reconnect: &reconnect}
+
+		size, err := codec.Send(server, sent)
+		require.NoError(t, err, name)
+
+		var received cableMsg
+		receivedSize, err := codec.Receive(client, &received)
+		require.NoError(t, err, name)
+
+		assert.Equal(t, size, receivedSize, name)
+		assert.Equal(t, sent.Type, received.Type, name)
+		assert.Equal(t, sent.Identifier, received.Identifier, name)
+		assert.Equal(t, sent.Message, received.Message, name)
+		assert.Equal(t, sent.Reason, received.Reason, name)
+		require.NotNil(t, received.Reconnect, name)
+		assert.False(t, *received.Reconnect, name)
+	}
+}
+
+func TestProtobufCodecRoundTrip(t *testing.T) {
+	client, server := wsPair(t)
+
+	// Commands are sent as binary frames
+	size, err := ProtobufCodec.Send(client, &cableMsg{Command: "message", Identifier: "id", Data: `{"action":"echo"}`})
+	require.NoError(t, err)
+
+	mtype, raw, err := server.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, mtype)
+	assert.Equal(t, size, len(raw))
+
+	var cmd pb.Message
+	require.NoError(t, proto.Unmarshal(raw, &cmd))
+	assert.Equal(t, pb.Command_message, cmd.Command)
+	assert.Equal(t, `{"action":"echo"}`, cmd.Data)
+
+	payload, err := msgpack.Marshal(map[string]interface{}{"action": "echo"})
+	require.NoError(t, err)
+
+	raw, err = proto.Marshal(&pb.Message{Identifier: cmd.Identifier, Message: payload})
+	require.NoError(t, err)
+	require.NoError(t, server.WriteMessage(websocket.BinaryMessage, raw))
+
+	var msg cableMsg
+	_, err = ProtobufCodec.Receive(client, &msg)
+	require.NoError(t, err)
+	assert.Equal(t, "id", msg.Identifier)
+	assert.Equal(t, map[string]interface{}{"action": "echo"}, msg.Message)
+
+	// Text frames are rejected
+	require.NoError(t, server.WriteMessage(websocket.TextMessage, raw))
+
+	_, err = ProtobufCodec.Receive(client, &msg)
+	assert.ErrorContains(t, err, "Unexpected message type")
+}
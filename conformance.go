@@ -0,0 +1,784 @@
+package cable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultConformanceChannel          = "EchoChannel"
+	defaultConformanceWelcomeTimeoutMs = 1000
+	defaultConformancePingIntervalMs   = 3000
+	defaultConformancePingToleranceMs  = 1000
+	defaultConformancePings            = 2
+	defaultConformanceWaitMs           = 500
+
+	conformancePassed  = "passed"
+	conformanceFailed  = "failed"
+	conformanceSkipped = "skipped"
+)
+
+var conformanceChecks = []string{
+	"welcome",
+	"ping",
+	"confirm",
+	"duplicate_subscribe",
+	"reject",
+	"unsubscribe",
+	"disconnect",
+	"codec",
+}
+
+type conformanceOptions struct {
+	Channel            string                 `json:"channel"`
+	Params             map[string]interface{} `json:"params"`
+	RejectChannel      string                 `json:"rejectChannel"`
+	RejectParams       map[string]interface{} `json:"rejectParams"`
+	DuplicateSubscribe string                 `json:"duplicateSubscribe"`
+	Codecs             []string               `json:"codecs"`
+	Checks             []string               `json:"checks"`
+	WelcomeTimeoutMs   int                    `json:"welcomeTimeoutMs"`
+	PingIntervalMs     int                    `json:"pingIntervalMs"`
+	PingToleranceMs    int                    `json:"pingToleranceMs"`
+	Pings              int                    `json:"pings"`
+	TimeoutMs          int                    `json:"timeoutMs"`
+	WaitMs             int                    `json:"waitMs"`
+	DisconnectURL      string                 `json:"disconnectUrl"`
+	// DisconnectConnectOptions are used to establish a connection which must be rejected by the server
+	// (e.g., without authentication cookies)
+	DisconnectConnectOptions json.RawMessage `json:"disconnectConnectOptions"`
+	ConnectOptions           json.RawMessage `json:"connectOptions"`
+}
+
+// ConformanceReport contains the results of the protocol conformance checks
+type ConformanceReport struct {
+	URL     string              `js:"url"`
+	Codec   string              `js:"codec"`
+	Passed  bool                `js:"passed"`
+	Failed  int                 `js:"failed"`
+	Skipped int                 `js:"skipped"`
+	Checks  []*ConformanceCheck `js:"checks"`
+}
+
+// ConformanceCheck describes the result of the particular check
+type ConformanceCheck struct {
+	Name   string `js:"name"`
+	Status string `js:"status"`
+	// Observed describes the server behaviour (e.g., welcome latency, ping interval, duplicate subscribe handling)
+	Observed string `js:"observed"`
+	// Message contains the failure (or skip) reason
+	Message  string `js:"message"`
+	Duration int64  `js:"duration"`
+}
+
+// Conformance runs the scripted suite of Action Cable protocol checks against the server
+// and returns the pass/fail report.
+func (c *Cable) Conformance(cableUrl string, optsIn sobek.Value) (*ConformanceReport, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, errCableInInitContext
+	}
+
+	var opts conformanceOptions
+	if err := decodeOptions(c.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	cOpts, err := decodeConnectOptions(opts.ConnectOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if pusher, _ := cOpts.pusher(); pusher {
+		return nil, fmt.Errorf("conformance checks are only supported for the Action Cable protocol")
+	}
+
+	for _, name := range opts.Checks {
+		if !isConformanceCheck(name) {
+			return nil, fmt.Errorf("unknown check: %s (supported checks: %s)", name, strings.Join(conformanceChecks, ", "))
+		}
+	}
+
+	for _, codec := range opts.Codecs {
+		if codec != "json" && codec != "msgpack" && codec != "protobuf" {
+			return nil, fmt.Errorf("unsupported codec: %s (supported values: json, msgpack, protobuf)", codec)
+		}
+	}
+
+	switch opts.DuplicateSubscribe {
+	case "", "ignore", "reject", "confirm":
+	default:
+		return nil, fmt.Errorf("unsupported duplicateSubscribe value: %s (supported values: ignore, reject, confirm)", opts.DuplicateSubscribe)
+	}
+
+	suite := &conformanceSuite{
+		cable:  c,
+		url:    cableUrl,
+		opts:   &opts,
+		cOpts:  cOpts,
+		logger: createLogger(state, cOpts),
+		report: &ConformanceReport{URL: cableUrl, Codec: codecName(cOpts.codec())},
+	}
+
+	suite.setDefaults()
+	suite.run()
+
+	report := suite.report
+	report.Passed = report.Failed == 0
+
+	return report, nil
+}
+
+func isConformanceCheck(name string) bool {
+	for _, check := range conformanceChecks {
+		if check == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type conformanceSuite struct {
+	cable  *Cable
+	url    string
+	opts   *conformanceOptions
+	cOpts  *connectOptions
+	logger *logrus.Entry
+	report *ConformanceReport
+
+	identifier string
+}
+
+func (s *conformanceSuite) setDefaults() {
+	o := s.opts
+
+	if o.Channel == "" {
+		o.Channel = defaultConformanceChannel
+	}
+
+	if o.WelcomeTimeoutMs <= 0 {
+		o.WelcomeTimeoutMs = defaultConformanceWelcomeTimeoutMs
+	}
+
+	if o.PingIntervalMs <= 0 {
+		o.PingIntervalMs = defaultConformancePingIntervalMs
+	}
+
+	if o.PingToleranceMs <= 0 {
+		o.PingToleranceMs = defaultConformancePingToleranceMs
+	}
+
+	if o.Pings <= 0 {
+		o.Pings = defaultConformancePings
+	}
+
+	if o.TimeoutMs <= 0 {
+		o.TimeoutMs = int(s.cOpts.receiveTimeout().Milliseconds())
+	}
+
+	if o.WaitMs <= 0 {
+		o.WaitMs = defaultConformanceWaitMs
+	}
+
+	if len(o.Codecs) == 0 {
+		o.Codecs = []string{codecName(s.cOpts.codec())}
+	}
+}
+
+func (s *conformanceSuite) enabled(name string) bool {
+	if len(s.opts.Checks) == 0 {
+		return true
+	}
+
+	for _, check := range s.opts.Checks {
+		if check == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check runs the check (if enabled) and adds the result to the report.
+// The check function returns the observed behaviour and the failure (if any).
+// Checks could be qualified (e.g., "codec:msgpack"); the qualifier is ignored when selecting checks.
+func (s *conformanceSuite) check(name string, fn func() (string, error)) bool {
+	if !s.enabled(strings.SplitN(name, ":", 2)[0]) {
+		return false
+	}
+
+	start := time.Now()
+	observed, err := fn()
+
+	result := &ConformanceCheck{
+		Name:     name,
+		Status:   conformancePassed,
+		Observed: observed,
+		Duration: time.Since(start).Milliseconds(),
+	}
+
+	var skip *conformanceSkip
+
+	if errors.As(err, &skip) {
+		result.Status = conformanceSkipped
+		result.Message = skip.reason
+		s.report.Skipped++
+	} else if err != nil {
+		result.Status = conformanceFailed
+		result.Message = err.Error()
+		s.report.Failed++
+		s.logger.Warnf("conformance check %s failed: %v", name, err)
+	}
+
+	s.report.Checks = append(s.report.Checks, result)
+
+	return result.Status == conformancePassed
+}
+
+// skipAll marks the remaining checks as skipped (e.g., when the connection failed)
+func (s *conformanceSuite) skipAll(names []string, reason string) {
+	for _, name := range names {
+		s.check(name, func() (string, error) {
+			return "", &conformanceSkip{reason}
+		})
+	}
+}
+
+type conformanceSkip struct {
+	reason string
+}
+
+func (e *conformanceSkip) Error() string {
+	return e.reason
+}
+
+func (s *conformanceSuite) run() {
+	s.identifier = conformanceIdentifier(s.opts.Channel, s.opts.Params)
+
+	session, err := s.open(s.url, s.cOpts)
+	if err != nil {
+		s.check("welcome", func() (string, error) { return "", err })
+		s.skipAll([]string{"ping", "confirm", "duplicate_subscribe", "reject", "unsubscribe"}, "connection failed")
+	} else {
+		s.runSession(session)
+		session.close()
+	}
+
+	s.check("disconnect", s.checkDisconnect)
+
+	for _, codec := range s.opts.Codecs {
+		codec := codec
+		s.check("codec:"+codec, func() (string, error) { return s.checkCodec(codec) })
+	}
+}
+
+func (s *conformanceSuite) runSession(session *conformanceSession) {
+	welcomed := s.check("welcome", func() (string, error) { return s.checkWelcome(session) })
+
+	// Welcome is required for all the other checks, so we must wait for it even if the check is disabled
+	if !s.enabled("welcome") {
+		_, err := s.checkWelcome(session)
+		welcomed = err == nil
+	}
+
+	if !welcomed {
+		s.skipAll([]string{"ping", "confirm", "duplicate_subscribe", "reject", "unsubscribe"}, "welcome message hasn't been received")
+		return
+	}
+
+	s.check("ping", func() (string, error) { return s.checkPing(session) })
+
+	confirmed := s.check("confirm", func() (string, error) { return s.checkConfirm(session) })
+
+	if !s.enabled("confirm") {
+		_, err := s.checkConfirm(session)
+		confirmed = err == nil
+	}
+
+	if confirmed {
+		s.check("duplicate_subscribe", func() (string, error) { return s.checkDuplicateSubscribe(session) })
+	} else {
+		s.skipAll([]string{"duplicate_subscribe"}, "subscription hasn't been confirmed")
+	}
+
+	s.check("reject", func() (string, error) { return s.checkReject(session) })
+
+	if confirmed {
+		s.check("unsubscribe", func() (string, error) { return s.checkUnsubscribe(session) })
+	} else {
+		s.skipAll([]string{"unsubscribe"}, "subscription hasn't been confirmed")
+	}
+}
+
+func (s *conformanceSuite) checkWelcome(session *conformanceSession) (string, error) {
+	timeout := time.Duration(s.opts.WelcomeTimeoutMs) * time.Millisecond
+
+	msg, err := session.next(timeout)
+	if err != nil {
+		return "", err
+	}
+
+	if msg == nil {
+		return "", fmt.Errorf("welcome message hasn't been received in %dms", s.opts.WelcomeTimeoutMs)
+	}
+
+	latency := msg.receivedAt.Sub(session.connectedAt).Milliseconds()
+	observed := fmt.Sprintf("welcome received in %dms", latency)
+
+	if session.firstType != "welcome" {
+		return observed, fmt.Errorf("the first message must be welcome, got: %s", session.firstType)
+	}
+
+	if msg.Type != "welcome" {
+		return observed, fmt.Errorf("expected welcome message, got: %s", msg.Type)
+	}
+
+	return observed, nil
+}
+
+func (s *conformanceSuite) checkPing(session *conformanceSession) (string, error) {
+	interval := time.Duration(s.opts.PingIntervalMs) * time.Millisecond
+	tolerance := time.Duration(s.opts.PingToleranceMs) * time.Millisecond
+	deadline := time.After(time.Duration(s.opts.Pings+1) * (interval + tolerance))
+
+	var pings []*cableMsg
+
+	for len(pings) <= s.opts.Pings {
+		select {
+		case ping := <-session.pings:
+			pings = append(pings, ping)
+		case <-deadline:
+			return fmt.Sprintf("%d pings received", len(pings)), fmt.Errorf("expected %d pings in %v", s.opts.Pings+1, time.Duration(s.opts.Pings+1)*(interval+tolerance))
+		}
+	}
+
+	var intervals []string
+	var total time.Duration
+
+	for i := 1; i < len(pings); i++ {
+		d := pings[i].receivedAt.Sub(pings[i-1].receivedAt)
+		total += d
+		intervals = append(intervals, fmt.Sprintf("%dms", d.Milliseconds()))
+	}
+
+	observed := fmt.Sprintf("ping intervals: %s (avg %dms)", strings.Join(intervals, ", "), (total / time.Duration(len(pings)-1)).Milliseconds())
+
+	for i := 1; i < len(pings); i++ {
+		d := pings[i].receivedAt.Sub(pings[i-1].receivedAt)
+		if d < interval-tolerance || d > interval+tolerance {
+			return observed, fmt.Errorf("ping interval %dms is out of the expected range (%v ± %v)", d.Milliseconds(), interval, tolerance)
+		}
+	}
+
+	for _, ping := range pings {
+		if _, ok := toFloat64(ping.Message); !ok {
+			return observed, fmt.Errorf("ping message must contain a numeric timestamp, got: %v", ping.Message)
+		}
+	}
+
+	return observed, nil
+}
+
+func (s *conformanceSuite) checkConfirm(session *conformanceSession) (string, error) {
+	if err := session.send(&cableMsg{Command: "subscribe", Identifier: s.identifier}); err != nil {
+		return "", err
+	}
+
+	msg, err := session.expect(s.timeout(), s.ackFor(s.identifier))
+	if err != nil {
+		return "", err
+	}
+
+	if msg == nil {
+		return "", fmt.Errorf("subscription to %s hasn't been acknowledged in %dms", s.identifier, s.opts.TimeoutMs)
+	}
+
+	observed := fmt.Sprintf("%s received in %dms", msg.Type, msg.receivedAt.Sub(session.sentAt).Milliseconds())
+
+	if msg.Type != "confirm_subscription" {
+		return observed, fmt.Errorf("subscription to %s has been rejected", s.identifier)
+	}
+
+	return observed, nil
+}
+
+func (s *conformanceSuite) checkDuplicateSubscribe(session *conformanceSession) (string, error) {
+	if err := session.send(&cableMsg{Command: "subscribe", Identifier: s.identifier}); err != nil {
+		return "", err
+	}
+
+	msg, err := session.expect(s.wait(), s.ackFor(s.identifier))
+	if err != nil {
+		return "", fmt.Errorf("connection has been closed after duplicate subscribe: %w", err)
+	}
+
+	behaviour := "ignore"
+	if msg != nil {
+		if msg.Type == "confirm_subscription" {
+			behaviour = "confirm"
+		} else {
+			behaviour = "reject"
+		}
+	}
+
+	observed := fmt.Sprintf("duplicate subscribe: %s", behaviour)
+
+	if s.opts.DuplicateSubscribe != "" && s.opts.DuplicateSubscribe != behaviour {
+		return observed, fmt.Errorf("expected duplicate subscribe to %s, got: %s", s.opts.DuplicateSubscribe, behaviour)
+	}
+
+	return observed, nil
+}
+
+func (s *conformanceSuite) checkReject(session *conformanceSession) (string, error) {
+	if s.opts.RejectChannel == "" {
+		return "", &conformanceSkip{"rejectChannel option is not provided"}
+	}
+
+	identifier := conformanceIdentifier(s.opts.RejectChannel, s.opts.RejectParams)
+
+	if err := session.send(&cableMsg{Command: "subscribe", Identifier: identifier}); err != nil {
+		return "", err
+	}
+
+	msg, err := session.expect(s.timeout(), s.ackFor(identifier))
+	if err != nil {
+		return "", err
+	}
+
+	if msg == nil {
+		return "", fmt.Errorf("subscription to %s hasn't been acknowledged in %dms", identifier, s.opts.TimeoutMs)
+	}
+
+	observed := fmt.Sprintf("%s received in %dms", msg.Type, msg.receivedAt.Sub(session.sentAt).Milliseconds())
+
+	if msg.Type != "reject_subscription" {
+		return observed, fmt.Errorf("subscription to %s has been confirmed", identifier)
+	}
+
+	return observed, nil
+}
+
+// checkUnsubscribe verifies that no messages are delivered after unsubscribing
+// and that it's possible to subscribe to the same channel again
+func (s *conformanceSuite) checkUnsubscribe(session *conformanceSession) (string, error) {
+	if err := session.send(&cableMsg{Command: "unsubscribe", Identifier: s.identifier}); err != nil {
+		return "", err
+	}
+
+	msg, err := session.expect(s.wait(), func(msg *cableMsg) bool { return msg.Identifier == s.identifier })
+	if err != nil {
+		return "", fmt.Errorf("connection has been closed after unsubscribe: %w", err)
+	}
+
+	if msg != nil {
+		return "", fmt.Errorf("received %s message for %s after unsubscribing", conformanceMessageType(msg), s.identifier)
+	}
+
+	if err := session.send(&cableMsg{Command: "subscribe", Identifier: s.identifier}); err != nil {
+		return "", err
+	}
+
+	msg, err = session.expect(s.timeout(), s.ackFor(s.identifier))
+	if err != nil {
+		return "", err
+	}
+
+	if msg == nil {
+		return "", fmt.Errorf("re-subscription to %s hasn't been acknowledged in %dms", s.identifier, s.opts.TimeoutMs)
+	}
+
+	observed := fmt.Sprintf("re-subscribe: %s", msg.Type)
+
+	if msg.Type != "confirm_subscription" {
+		return observed, fmt.Errorf("re-subscription to %s has been rejected", s.identifier)
+	}
+
+	return observed, nil
+}
+
+// checkDisconnect verifies that the server sends the disconnect message (with the reason and reconnect flag)
+// and closes the connection when it's rejected
+func (s *conformanceSuite) checkDisconnect() (string, error) {
+	if s.opts.DisconnectURL == "" && len(s.opts.DisconnectConnectOptions) == 0 {
+		return "", &conformanceSkip{"neither disconnectUrl nor disconnectConnectOptions option is provided"}
+	}
+
+	cableUrl := s.opts.DisconnectURL
+	if cableUrl == "" {
+		cableUrl = s.url
+	}
+
+	cOpts := s.cOpts
+	if len(s.opts.DisconnectConnectOptions) > 0 {
+		var err error
+		if cOpts, err = decodeConnectOptions(s.opts.DisconnectConnectOptions); err != nil {
+			return "", err
+		}
+	}
+
+	session, err := s.open(cableUrl, cOpts)
+	if err != nil {
+		return "", err
+	}
+	defer session.close()
+
+	msg, err := session.expect(time.Duration(s.opts.WelcomeTimeoutMs)*time.Millisecond, func(msg *cableMsg) bool { return msg.Type == "disconnect" })
+	if err != nil {
+		return "", fmt.Errorf("connection has been closed without the disconnect message: %w", err)
+	}
+
+	if msg == nil {
+		return "", fmt.Errorf("disconnect message hasn't been received in %dms", s.opts.WelcomeTimeoutMs)
+	}
+
+	reconnect := "<missing>"
+	if msg.Reconnect != nil {
+		reconnect = fmt.Sprintf("%v", *msg.Reconnect)
+	}
+
+	observed := fmt.Sprintf("disconnect: reason=%s reconnect=%s", msg.Reason, reconnect)
+
+	if msg.Reason == "" {
+		return observed, fmt.Errorf("disconnect message must contain the reason")
+	}
+
+	if msg.Reconnect == nil {
+		return observed, fmt.Errorf("disconnect message must contain the reconnect flag")
+	}
+
+	if !session.waitClosed(s.timeout()) {
+		return observed, fmt.Errorf("connection hasn't been closed in %dms after the disconnect message", s.opts.TimeoutMs)
+	}
+
+	return observed, nil
+}
+
+// checkCodec verifies that the server negotiates the codec subprotocol and sends the welcome message encoded with it
+func (s *conformanceSuite) checkCodec(codec string) (string, error) {
+	cOpts := *s.cOpts
+	cOpts.Codec = codec
+
+	session, err := s.open(s.url, &cOpts)
+	if err != nil {
+		return "", err
+	}
+	defer session.close()
+
+	expected := "actioncable-v1-" + codec
+	protocol := ""
+
+	if session.response != nil {
+		protocol = session.response.Header.Get("Sec-WebSocket-Protocol")
+	}
+
+	observed := fmt.Sprintf("subprotocol: %q", protocol)
+
+	if protocol != expected {
+		return observed, fmt.Errorf("expected %s subprotocol", expected)
+	}
+
+	msg, err := session.expect(time.Duration(s.opts.WelcomeTimeoutMs)*time.Millisecond, func(msg *cableMsg) bool { return msg.Type == "welcome" })
+	if err != nil {
+		return observed, err
+	}
+
+	if msg == nil {
+		return observed, fmt.Errorf("welcome message hasn't been received in %dms", s.opts.WelcomeTimeoutMs)
+	}
+
+	return observed, nil
+}
+
+func (s *conformanceSuite) timeout() time.Duration {
+	return time.Duration(s.opts.TimeoutMs) * time.Millisecond
+}
+
+func (s *conformanceSuite) wait() time.Duration {
+	return time.Duration(s.opts.WaitMs) * time.Millisecond
+}
+
+func (s *conformanceSuite) ackFor(identifier string) func(*cableMsg) bool {
+	return func(msg *cableMsg) bool {
+		return msg.Identifier == identifier && (msg.Type == "confirm_subscription" || msg.Type == "reject_subscription")
+	}
+}
+
+func (s *conformanceSuite) open(cableUrl string, cOpts *connectOptions) (*conformanceSession, error) {
+	client, resp, err := s.cable.dial(cableUrl, cOpts, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &conformanceSession{
+		transport:   client.transport,
+		response:    resp,
+		connectedAt: time.Now(),
+		msgs:        make(chan *cableMsg, 1024),
+		pings:       make(chan *cableMsg, 64),
+		closed:      make(chan struct{}),
+		logger:      s.logger,
+	}
+
+	go session.receiveLoop()
+
+	return session, nil
+}
+
+// conformanceSession reads messages from the transport directly (bypassing the client),
+// so that all the protocol messages (including pings) could be inspected
+type conformanceSession struct {
+	transport   transport
+	response    *http.Response
+	connectedAt time.Time
+	sentAt      time.Time
+	logger      *logrus.Entry
+
+	// firstType is the type of the first received message; must only be read after receiving it
+	firstType string
+
+	msgs   chan *cableMsg
+	pings  chan *cableMsg
+	closed chan struct{}
+	err    error
+}
+
+func (s *conformanceSession) receiveLoop() {
+	defer close(s.closed)
+
+	first := true
+
+	for {
+		msg := &cableMsg{}
+
+		_, err := s.transport.Receive(msg)
+		if err != nil {
+			var derr *decodeError
+			if errors.As(err, &derr) {
+				s.logger.Warnf("failed to decode incoming message: %v", err)
+				continue
+			}
+
+			s.err = err
+			return
+		}
+
+		msg.receivedAt = time.Now()
+
+		if first {
+			s.firstType = msg.Type
+			first = false
+		}
+
+		ch := s.msgs
+		if msg.Type == "ping" {
+			ch = s.pings
+		}
+
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+func (s *conformanceSession) send(msg *cableMsg) error {
+	s.sentAt = time.Now()
+	_, err := s.transport.Send(msg)
+	return err
+}
+
+// next returns the next non-ping message, or nil if no messages received in time.
+// Returns an error if the connection has been closed.
+func (s *conformanceSession) next(timeout time.Duration) (*cableMsg, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-s.msgs:
+		return msg, nil
+	case <-s.closed:
+		// Make sure all the messages received before closing are consumed
+		select {
+		case msg := <-s.msgs:
+			return msg, nil
+		default:
+		}
+		return nil, s.closeError()
+	case <-timer.C:
+		return nil, nil
+	}
+}
+
+// expect returns the first message matching the predicate (other messages are skipped),
+// or nil if no such messages received in time
+func (s *conformanceSession) expect(timeout time.Duration, match func(*cableMsg) bool) (*cableMsg, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		left := time.Until(deadline)
+		if left <= 0 {
+			return nil, nil
+		}
+
+		msg, err := s.next(left)
+		if err != nil || msg == nil {
+			return nil, err
+		}
+
+		if match(msg) {
+			return msg, nil
+		}
+	}
+}
+
+func (s *conformanceSession) waitClosed(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.closed:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (s *conformanceSession) closeError() error {
+	if s.err != nil {
+		return fmt.Errorf("connection closed: %w", s.err)
+	}
+
+	return fmt.Errorf("connection closed")
+}
+
+func (s *conformanceSession) close() {
+	_ = s.transport.Close()
+	<-s.closed
+}
+
+func conformanceIdentifier(channel string, params map[string]interface{}) string {
+	identifier := map[string]interface{}{"channel": channel}
+	for k, v := range params {
+		identifier[k] = v
+	}
+
+	b, _ := json.Marshal(identifier)
+	return string(b)
+}
+
+func conformanceMessageType(msg *cableMsg) string {
+	if msg.Type != "" {
+		return msg.Type
+	}
+
+	return "broadcast"
+}
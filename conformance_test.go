@@ -0,0 +1,77 @@
+package cable
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+func TestConformance(t *testing.T) {
+	ts := newTestState(t)
+
+	config := echoServerConfig()
+	config.PingInterval = 100 * time.Millisecond
+	config.Authenticate = func(r *http.Request) bool {
+		_, err := r.Cookie("user_id")
+		return err == nil
+	}
+
+	ts.startMockServer(t, config)
+
+	report := ts.run(t, `
+		cable.conformance(CABLE_URL, {
+			rejectChannel: "RejectChannel",
+			duplicateSubscribe: "ignore",
+			pingIntervalMs: 100,
+			pingToleranceMs: 50,
+			waitMs: 100,
+			codecs: ["json", "msgpack", "protobuf"],
+			disconnectConnectOptions: {},
+			connectOptions: { cookies: "user_id=42" },
+		})
+	`).Export().(*ConformanceReport)
+
+	for _, check := range report.Checks {
+		assert.Equal(t, conformancePassed, check.Status, "%s: %s", check.Name, check.Message)
+	}
+
+	assert.True(t, report.Passed)
+	assert.Len(t, report.Checks, 10)
+}
+
+func TestConformanceFailures(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, mockserver.Config{
+		// No pings and all subscriptions are rejected
+		Channels: map[string]*mockserver.Channel{"EchoChannel": {Reject: true}},
+	})
+
+	report := ts.run(t, `
+		cable.conformance(CABLE_URL, {
+			checks: ["welcome", "ping", "confirm", "duplicate_subscribe", "reject"],
+			pingIntervalMs: 50,
+			pingToleranceMs: 10,
+			pings: 1,
+		})
+	`).Export().(*ConformanceReport)
+
+	require.False(t, report.Passed)
+
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+
+	assert.Equal(t, map[string]string{
+		"welcome":             conformancePassed,
+		"ping":                conformanceFailed,
+		"confirm":             conformanceFailed,
+		"duplicate_subscribe": conformanceSkipped,
+		"reject":              conformanceSkipped,
+	}, statuses)
+}
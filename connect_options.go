@@ -3,6 +3,7 @@ package cable
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -15,6 +16,22 @@ type connectOptions struct {
 	Tags    map[string]string `json:"tags"`
 	Codec   string            `json:"codec"`
 
+	Compression string `json:"compression"`
+	PerformURL  string `json:"performUrl"`
+	// ChannelParams are the SSE URL query params to include into the channel identifier (along with `channel`)
+	ChannelParams []string `json:"channelParams"`
+
+	Protocol string         `json:"protocol"`
+	Pusher   *pusherOptions `json:"pusher"`
+
+	// Record is the transcript path (if ends with .jsonl) or the prefix for per-client transcripts
+	Record           string   `json:"record"`
+	RecordSampleRate *float64 `json:"recordSampleRate"`
+	RecordRedact     []string `json:"recordRedact"`
+
+	// SkipInvalidFrames makes the client log and skip incoming frames that couldn't be decoded
+	SkipInvalidFrames bool `json:"skipInvalidFrames"`
+
 	HandshakeTimeoutS int    `json:"handshakeTimeoutS"`
 	ReceiveTimeoutMs  int    `json:"receiveTimeoutMs"`
 	LogLevel          string `json:"logLevel"`
@@ -28,22 +45,55 @@ const (
 func parseOptions(rt *sobek.Runtime, inOpts sobek.Value) (*connectOptions, error) {
 	var outOpts connectOptions
 
+	if err := decodeOptions(rt, inOpts, &outOpts); err != nil {
+		return nil, err
+	}
+
+	return &outOpts, nil
+}
+
+// decodeOptions converts JS object into the provided options struct (unknown fields are not allowed)
+func decodeOptions(rt *sobek.Runtime, inOpts sobek.Value, outOpts interface{}) error {
 	if inOpts == nil || sobek.IsUndefined(inOpts) || sobek.IsNull(inOpts) {
-		return &outOpts, nil
+		return nil
 	}
 
 	data, err := json.Marshal(inOpts.ToObject(rt).Export())
 	if err != nil {
-		return nil, err
+		return err
 	}
 	dec := json.NewDecoder(bytes.NewReader(data))
 	dec.DisallowUnknownFields()
-	if err := dec.Decode(&outOpts); err != nil {
-		if uerr := json.Unmarshal(data, &outOpts); uerr != nil {
-			return nil, uerr
+	if err := dec.Decode(outOpts); err != nil {
+		if uerr := json.Unmarshal(data, outOpts); uerr != nil {
+			return uerr
+		}
+		return err
+	}
+	return nil
+}
+
+// decodeConnectOptions decodes and validates connect options passed as a nested JSON object
+// (e.g., the `connectOptions` option of the fuzzing and conformance modes)
+func decodeConnectOptions(raw json.RawMessage) (*connectOptions, error) {
+	var outOpts connectOptions
+
+	if len(raw) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&outOpts); err != nil {
+			return nil, err
 		}
+	}
+
+	if _, err := outOpts.compression(); err != nil {
 		return nil, err
 	}
+
+	if _, err := outOpts.pusher(); err != nil {
+		return nil, err
+	}
+
 	return &outOpts, nil
 }
 
@@ -57,6 +107,31 @@ func (co *connectOptions) codec() *Codec {
 	return JSONCodec
 }
 
+func (co *connectOptions) pusher() (bool, error) {
+	switch co.Protocol {
+	case "", "actioncable":
+		return false, nil
+	case "pusher":
+		if co.codec() != JSONCodec {
+			return false, fmt.Errorf("pusher protocol only supports json codec")
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported protocol: %s (supported values: actioncable, pusher)", co.Protocol)
+	}
+}
+
+func (co *connectOptions) compression() (bool, error) {
+	switch co.Compression {
+	case "":
+		return false, nil
+	case "deflate":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported compression: %s (supported values: deflate)", co.Compression)
+	}
+}
+
 func (co *connectOptions) handshakeTimeout() time.Duration {
 	if co.HandshakeTimeoutS == 0 {
 		return defaultHandshakeTimeout * time.Second
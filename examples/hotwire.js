@@ -86,9 +86,9 @@ export default function () {
     // Msg here is an HTML element (<turbo-stream>),
     // we use data attributes to indicate the message author,
     // so, here we're looking for our messages
-    let message = channel.receive((msg) => {
-      return msg.includes(`data-author-id="${userId}"`);
-    });
+    let message = channel.receive(
+      cable.turbo({ action: "append", template: `data-author-id="${userId}"` })
+    );
 
     if (
       !check(message, {
@@ -0,0 +1,29 @@
+import { check, fail } from "k6";
+import cable from "k6/x/cable";
+
+export default function () {
+  // Subscription is defined by the URL params
+  const client = cable.connectSSE(
+    "http://localhost:8080/events?channel=BenchmarkChannel",
+    // Commands (performs) are sent via HTTP POST requests (to the connection URL by default)
+    { performUrl: "http://localhost:8080/events" }
+  );
+
+  if (
+    !check(client, {
+      "successful connection": (obj) => obj,
+    })
+  ) {
+    fail("connection failed");
+  }
+
+  const channel = client.subscribe("BenchmarkChannel");
+
+  channel.perform("echo", { foo: 1 });
+  const res = channel.receive({ foo: 1 });
+  check(res, {
+    "received res": (obj) => obj && obj.foo === 1,
+  });
+
+  client.disconnect();
+}
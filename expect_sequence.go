@@ -0,0 +1,372 @@
+package cable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/pmezard/go-difflib/difflib"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+// unorderedKey marks the segment of the expected sequence which could be received in any order
+const unorderedKey = "$unordered"
+
+type expectSequenceOptions struct {
+	// Ignore contains dotted paths of volatile fields ("*" matches any key or array element)
+	Ignore []string `json:"ignore"`
+	// Skip contains attributes of messages which are not a part of the sequence (e.g., presence updates)
+	Skip      map[string]interface{} `json:"skip"`
+	TimeoutMs int                    `json:"timeoutMs"`
+	// Check is the name of the k6 check to record the result to
+	Check string `json:"check"`
+}
+
+// ExpectSequenceResult contains the result of the sequence comparison
+type ExpectSequenceResult struct {
+	OK       bool          `js:"ok"`
+	Expected int           `js:"expected"`
+	Received []interface{} `js:"received"`
+	// Diff is the unified diff between expected and received messages (one message per line); empty if OK
+	Diff string `js:"diff"`
+}
+
+// sequenceSegment is either a single message or a group of messages received in any order
+type sequenceSegment struct {
+	matchers []*ExactMatcher
+}
+
+// ExpectSequence receives messages and compares them with the expected sequence (an array or a path to a golden file).
+// The golden file contains either a JSON array or a message per line (JSONL).
+// Objects like `{"$unordered": [...]}` describe segments which could be received in any order.
+func (ch *Channel) ExpectSequence(expectedIn sobek.Value, optsIn sobek.Value) (*ExpectSequenceResult, error) {
+	var opts expectSequenceOptions
+	if err := decodeOptions(ch.client.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	expected, err := ch.loadSequence(expectedIn)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(opts.Check, lib.GroupSeparator) {
+		return nil, lib.ErrNameContainsGroupSeparator
+	}
+
+	// Client-side receive timestamp is always volatile
+	ignore := append(append([]string(nil), opts.Ignore...), "__timestamp__")
+
+	segments, total, err := buildSequence(expected, ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := ch.client.recTimeout
+	if opts.TimeoutMs > 0 {
+		timeout = time.Duration(opts.TimeoutMs) * time.Millisecond
+	}
+
+	var skip Matcher
+	if len(opts.Skip) > 0 {
+		if skip, err = NewAttrMatcher(opts.Skip); err != nil {
+			return nil, err
+		}
+	}
+
+	received := ch.receiveSequence(total, timeout, skip)
+
+	result := &ExpectSequenceResult{Expected: total, Received: received}
+	result.OK, result.Diff = compareSequence(segments, received, ignore)
+
+	if !result.OK {
+		ch.logger.Warnf("received messages don't match the expected sequence:\n%s", result.Diff)
+	}
+
+	if opts.Check != "" {
+		ch.trackCheck(opts.Check, result.OK)
+	}
+
+	return result, nil
+}
+
+// receiveSequence receives up to n messages (not matching the skip matcher) waiting up to timeout for each
+func (ch *Channel) receiveSequence(n int, timeout time.Duration, skip Matcher) []interface{} {
+	results := make([]interface{}, 0, n)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for len(results) < n {
+		msg := ch.next(timer.C)
+		if msg == nil {
+			ch.logger.Warn("receive timeout exceeded; consider increasing timeoutMs option")
+			break
+		}
+
+		timer.Reset(timeout)
+
+		if skip != nil && skip.Match(msg.Message) {
+			continue
+		}
+
+		results = append(results, msg.Message)
+	}
+
+	return results
+}
+
+// trackCheck emits the checks metric sample the same way k6 check() does: the end-of-test summary
+// aggregates named checks (within the current group) from the samples tags.
+// The check name is only added if the check system tag is enabled.
+func (ch *Channel) trackCheck(name string, ok bool) {
+	state := ch.client.vu.State()
+	tags := state.Tags.GetCurrentValues()
+
+	checkTags := tags.Tags
+	if state.Options.SystemTags.Has(metrics.TagCheck) {
+		checkTags = checkTags.With("check", name)
+	}
+
+	value := 0.0
+	if ok {
+		value = 1
+	}
+
+	metrics.PushIfNotDone(ch.client.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: state.BuiltinMetrics.Checks,
+			Tags:   checkTags,
+		},
+		Time:     time.Now(),
+		Metadata: tags.Metadata,
+		Value:    value,
+	})
+}
+
+// loadSequence returns the expected messages from the JS array or the golden file
+func (ch *Channel) loadSequence(val sobek.Value) ([]interface{}, error) {
+	if val == nil || sobek.IsUndefined(val) || sobek.IsNull(val) {
+		return nil, fmt.Errorf("expected sequence must be an array or a golden file path")
+	}
+
+	if path, ok := val.Export().(string); ok {
+		return loadGoldenFile(path)
+	}
+
+	encoded, err := val.ToObject(ch.client.vu.Runtime()).MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var expected []interface{}
+	if err := json.Unmarshal(encoded, &expected); err != nil {
+		return nil, fmt.Errorf("expected sequence must be an array: %w", err)
+	}
+
+	return expected, nil
+}
+
+func loadGoldenFile(path string) ([]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden file: %w", err)
+	}
+
+	var expected []interface{}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &expected); err != nil {
+			return nil, fmt.Errorf("invalid golden file %s: %w", path, err)
+		}
+
+		return expected, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var msg interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("invalid golden file %s at line %d: %w", path, line, err)
+		}
+
+		expected = append(expected, msg)
+	}
+
+	return expected, scanner.Err()
+}
+
+func buildSequence(expected []interface{}, ignore []string) ([]*sequenceSegment, int, error) {
+	segments := make([]*sequenceSegment, 0, len(expected))
+	total := 0
+
+	for _, item := range expected {
+		obj, ok := item.(map[string]interface{})
+		if !ok || obj[unorderedKey] == nil {
+			segments = append(segments, &sequenceSegment{matchers: []*ExactMatcher{NewExactMatcher(item, ignore)}})
+			total++
+			continue
+		}
+
+		group, ok := obj[unorderedKey].([]interface{})
+		if !ok || len(obj) > 1 {
+			return nil, 0, fmt.Errorf("%s segment must only contain an array of messages", unorderedKey)
+		}
+
+		segment := &sequenceSegment{}
+		for _, msg := range group {
+			segment.matchers = append(segment.matchers, NewExactMatcher(msg, ignore))
+		}
+
+		segments = append(segments, segment)
+		total += len(group)
+	}
+
+	return segments, total, nil
+}
+
+// compareSequence matches received messages against the segments and returns the unified diff if they don't match.
+// Messages of unordered segments are aligned with the expected ones before diffing.
+func compareSequence(segments []*sequenceSegment, received []interface{}, ignore []string) (bool, string) {
+	var want, got []string
+
+	pos := 0
+	for _, segment := range segments {
+		end := pos + len(segment.matchers)
+		if end > len(received) {
+			end = len(received)
+		}
+
+		window := received[pos:end]
+		pos = end
+
+		aligned := make([]interface{}, len(segment.matchers))
+		present := make([]bool, len(segment.matchers))
+		used := make([]bool, len(window))
+
+		for i, m := range segment.matchers {
+			for j, msg := range window {
+				if !used[j] && m.Match(msg) {
+					aligned[i], present[i], used[j] = msg, true, true
+					break
+				}
+			}
+		}
+
+		// Fill the gaps with the rest of received messages
+		j := 0
+		for i := range aligned {
+			if present[i] {
+				continue
+			}
+
+			for j < len(window) && used[j] {
+				j++
+			}
+
+			if j < len(window) {
+				aligned[i], present[i], used[j] = window[j], true, true
+			}
+		}
+
+		for i, m := range segment.matchers {
+			want = append(want, m.String())
+
+			if present[i] {
+				got = append(got, encodeForDiff(normalizeMessage(aligned[i], ignore)))
+			}
+		}
+	}
+
+	ok := len(want) == len(got)
+	for i := 0; ok && i < len(want); i++ {
+		ok = want[i] == got[i]
+	}
+
+	if ok {
+		return true, ""
+	}
+
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(want),
+		B:        diffLines(got),
+		FromFile: "expected",
+		ToFile:   "received",
+		Context:  3,
+	})
+
+	return false, diff
+}
+
+func diffLines(lines []string) []string {
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = line + "\n"
+	}
+
+	return result
+}
+
+// ExactMatcher matches messages equal to the expected one (ignoring volatile fields)
+type ExactMatcher struct {
+	expected interface{}
+	ignore   []string
+}
+
+func NewExactMatcher(expected interface{}, ignore []string) *ExactMatcher {
+	return &ExactMatcher{expected: normalizeMessage(expected, ignore), ignore: ignore}
+}
+
+func (m *ExactMatcher) Match(msg interface{}) bool {
+	return reflect.DeepEqual(m.expected, normalizeMessage(msg, m.ignore))
+}
+
+func (m *ExactMatcher) String() string {
+	return encodeForDiff(m.expected)
+}
+
+// normalizeMessage converts the message to the JSON representation (so messages decoded by different codecs are comparable)
+// and removes ignored fields
+func normalizeMessage(msg interface{}, ignore []string) interface{} {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return msg
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return msg
+	}
+
+	for _, path := range ignore {
+		deletePath(result, path)
+	}
+
+	return result
+}
+
+// encodeForDiff returns the canonical (sorted keys) JSON representation of the message
+func encodeForDiff(msg interface{}) string {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("%v", msg)
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,182 @@
+package cable
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+func TestExpectSequence(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	result := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { n: 1, id: "a1" });
+		channel.perform("echo", { n: 2, id: "b2" });
+		channel.perform("echo", { n: 3, id: "c3" });
+		channel.perform("echo", { n: 4, id: "d4" });
+
+		channel.expectSequence([
+			{ action: "echo", n: 1 },
+			{ $unordered: [{ action: "echo", n: 3 }, { action: "echo", n: 2 }] },
+			{ action: "echo", n: 4 },
+		], { ignore: ["id"], check: "sequence" });
+	`).Export().(*ExpectSequenceResult)
+
+	assert.True(t, result.OK)
+	assert.Empty(t, result.Diff)
+	assert.Equal(t, 4, result.Expected)
+	assert.Len(t, result.Received, 4)
+
+	checks := ts.requireMetric(t, "checks", map[string]string{"check": "sequence"})
+	assert.Equal(t, 1.0, checks[0].Value)
+}
+
+func TestExpectSequenceCheckSummary(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.VU.StateField.Tags.Modify(func(tagsAndMeta *metrics.TagsAndMeta) {
+		tagsAndMeta.SetSystemTagOrMeta(metrics.TagGroup, "::chat")
+	})
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { n: 1, id: "a1" });
+		channel.expectSequence([{ action: "echo", n: 1 }], { ignore: ["id"], check: "sequence" });
+
+		channel.perform("echo", { n: 2, id: "b2" });
+		channel.expectSequence([{ action: "echo", n: 3 }], { ignore: ["id"], timeoutMs: 200, check: "sequence" });
+	`)
+
+	_, err := ts.VU.Runtime().RunString(`channel.expectSequence([], { check: "chat::sequence" })`)
+	assert.ErrorContains(t, err, "group and check names may not contain")
+
+	// Checks are aggregated by the end-of-test summary the same way as k6 check() results
+	summary := lib.NewGroupSummary(ts.VU.StateField.Logger)
+	require.NoError(t, summary.Start())
+
+	summary.AddMetricSamples([]metrics.SampleContainer{metrics.Samples(ts.requireMetric(t, "checks", nil))})
+	require.NoError(t, summary.Stop())
+
+	group := summary.Group().Groups["chat"]
+	require.NotNil(t, group)
+	require.Contains(t, group.Checks, "sequence")
+
+	assert.EqualValues(t, 1, group.Checks["sequence"].Passes)
+	assert.EqualValues(t, 1, group.Checks["sequence"].Fails)
+}
+
+func TestExpectSequenceCheckTagDisabled(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.VU.StateField.Options.SystemTags = metrics.NewSystemTagSet(metrics.TagURL)
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { n: 1 });
+		channel.expectSequence([{ action: "echo", n: 1 }], { check: "sequence" });
+	`)
+
+	checks := ts.requireMetric(t, "checks", nil)
+	require.Len(t, checks, 1)
+
+	_, ok := checks[0].Tags.Get("check")
+	assert.False(t, ok)
+}
+
+func TestExpectSequenceDiff(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	result := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { n: 1 });
+		channel.perform("echo", { n: 5 });
+
+		channel.expectSequence([
+			{ action: "echo", n: 1 },
+			{ action: "echo", n: 2 },
+			{ action: "echo", n: 3 },
+		], { timeoutMs: 200, check: "sequence" });
+	`).Export().(*ExpectSequenceResult)
+
+	assert.False(t, result.OK)
+	assert.Len(t, result.Received, 2)
+	assert.Equal(t, `--- expected
++++ received
+@@ -1,3 +1,2 @@
+ {"action":"echo","n":1}
+-{"action":"echo","n":2}
+-{"action":"echo","n":3}
++{"action":"echo","n":5}
+`, result.Diff)
+
+	checks := ts.requireMetric(t, "checks", map[string]string{"check": "sequence"})
+	assert.Equal(t, 0.0, checks[0].Value)
+}
+
+func TestExpectSequenceGoldenFile(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	path := filepath.Join(t.TempDir(), "echo.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"action":"echo","user":{"name":"jack","created_at":1}}
+{"action":"echo","user":{"name":"jill","created_at":2}}
+`), 0o600))
+
+	require.NoError(t, ts.VU.Runtime().Set("GOLDEN", path))
+
+	result := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { user: { name: "jack", created_at: 100 } });
+		channel.perform("presence", { type: "presence" });
+		channel.perform("echo", { user: { name: "jill", created_at: 200 } });
+
+		channel.expectSequence(GOLDEN, { ignore: ["user.created_at"], skip: { type: "presence" } });
+	`).Export().(*ExpectSequenceResult)
+
+	assert.True(t, result.OK, result.Diff)
+}
+
+func TestCompareSequence(t *testing.T) {
+	ignore := []string{"items.*.id"}
+
+	segments, total, err := buildSequence([]interface{}{
+		map[string]interface{}{unorderedKey: []interface{}{"a", "b"}},
+		map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 1, "v": 1}}},
+	}, ignore)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	ok, diff := compareSequence(segments, []interface{}{
+		"b", "a",
+		map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 42, "v": 1}}},
+	}, ignore)
+	assert.True(t, ok, diff)
+
+	ok, diff = compareSequence(segments, []interface{}{"b", "c"}, ignore)
+	assert.False(t, ok)
+	assert.Contains(t, diff, "-\"a\"\n+\"c\"\n")
+	assert.Contains(t, diff, "-{\"items\":[{\"v\":1}]}\n")
+
+	_, _, err = buildSequence([]interface{}{map[string]interface{}{unorderedKey: "a"}}, nil)
+	assert.ErrorContains(t, err, "$unordered segment must only contain an array of messages")
+}
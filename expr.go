@@ -0,0 +1,663 @@
+package cable
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ExprMatcher matches messages against the expression evaluated natively (without calling into JS).
+// Matchers are built via `cable.where(expression)`.
+//
+// Expressions support:
+// - message fields access: msg.type, msg.data.user.id, msg.items[0], msg["content-type"]
+// - literals: numbers, strings ('single' or "double" quoted), true, false, null and arrays ([1, 2])
+// - comparisons: ==, !=, <, <=, >, >=
+// - membership: x in [1, 2] (or a substring in a string, or a key in an object)
+// - regular expressions: msg.text =~ "^hello" (and !~)
+// - boolean logic: &&, ||, ! and parentheses
+//
+// Missing fields evaluate to null.
+type ExprMatcher struct {
+	source string
+	root   exprNode
+}
+
+// compiledExprs caches compiled expressions (they're immutable and could be shared by VUs)
+var compiledExprs = newCompiledCache(maxCompiledCacheSize)
+
+// Where compiles the expression and returns the matcher to be used with receive functions
+// (e.g., `channel.receive(cable.where("msg.type == 'chat'"))`)
+func (c *Cable) Where(source string) (*ExprMatcher, error) {
+	return NewExprMatcher(source)
+}
+
+// NewExprMatcher compiles the expression (or returns the cached one)
+func NewExprMatcher(source string) (*ExprMatcher, error) {
+	if m, ok := compiledExprs.get(source); ok {
+		return m.(*ExprMatcher), nil
+	}
+
+	p := &exprParser{lexer: &exprLexer{src: source}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != exprTokenEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	m := &ExprMatcher{source: source, root: root}
+	compiledExprs.put(source, m)
+
+	return m, nil
+}
+
+func (m *ExprMatcher) Match(msg interface{}) bool {
+	return exprTruthy(m.root.eval(msg))
+}
+
+func (m *ExprMatcher) String() string {
+	return m.source
+}
+
+type exprNode interface {
+	eval(msg interface{}) interface{}
+}
+
+type exprLiteral struct {
+	val interface{}
+}
+
+func (n *exprLiteral) eval(_ interface{}) interface{} {
+	return n.val
+}
+
+// exprPath is the message field access; keys are either strings (object keys) or ints (array indices)
+type exprPath struct {
+	keys []interface{}
+}
+
+func (n *exprPath) eval(msg interface{}) interface{} {
+	val := msg
+
+	for _, key := range n.keys {
+		switch k := key.(type) {
+		case string:
+			obj, ok := val.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			val = obj[k]
+		case int:
+			arr, ok := val.([]interface{})
+			if !ok || k < 0 || k >= len(arr) {
+				return nil
+			}
+			val = arr[k]
+		}
+	}
+
+	return val
+}
+
+type exprArray struct {
+	items []exprNode
+}
+
+func (n *exprArray) eval(msg interface{}) interface{} {
+	result := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		result[i] = item.eval(msg)
+	}
+
+	return result
+}
+
+type exprUnary struct {
+	op string
+	x  exprNode
+}
+
+func (n *exprUnary) eval(msg interface{}) interface{} {
+	val := n.x.eval(msg)
+
+	if n.op == "-" {
+		if num, ok := toFloat64(val); ok {
+			return -num
+		}
+		return nil
+	}
+
+	return !exprTruthy(val)
+}
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *exprBinary) eval(msg interface{}) interface{} {
+	switch n.op {
+	case "&&":
+		return exprTruthy(n.left.eval(msg)) && exprTruthy(n.right.eval(msg))
+	case "||":
+		return exprTruthy(n.left.eval(msg)) || exprTruthy(n.right.eval(msg))
+	}
+
+	left, right := n.left.eval(msg), n.right.eval(msg)
+
+	switch n.op {
+	case "==":
+		return exprEqual(left, right)
+	case "!=":
+		return !exprEqual(left, right)
+	case "in":
+		return exprIn(left, right)
+	default:
+		return exprCompare(n.op, left, right)
+	}
+}
+
+type exprRegexp struct {
+	x      exprNode
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n *exprRegexp) eval(msg interface{}) interface{} {
+	str, ok := n.x.eval(msg).(string)
+	if !ok {
+		return false
+	}
+
+	return n.re.MatchString(str) != n.negate
+}
+
+func exprTruthy(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+
+	if num, ok := toFloat64(val); ok {
+		return num != 0
+	}
+
+	return true
+}
+
+// exprEqual compares values loosely regarding numeric types (so JSON and msgpack values are comparable)
+func exprEqual(a, b interface{}) bool {
+	if x, ok := toFloat64(a); ok {
+		y, ok := toFloat64(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for i := range x {
+			if !exprEqual(x[i], y[i]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for k, v := range x {
+			if !exprEqual(v, y[k]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return a == b
+}
+
+func exprIn(needle, haystack interface{}) bool {
+	switch h := haystack.(type) {
+	case []interface{}:
+		for _, item := range h {
+			if exprEqual(needle, item) {
+				return true
+			}
+		}
+	case string:
+		if str, ok := needle.(string); ok {
+			return strings.Contains(h, str)
+		}
+	case map[string]interface{}:
+		if key, ok := needle.(string); ok {
+			_, exists := h[key]
+			return exists
+		}
+	}
+
+	return false
+}
+
+func exprCompare(op string, a, b interface{}) bool {
+	var cmp int
+
+	if x, ok := toFloat64(a); ok {
+		y, ok := toFloat64(b)
+		if !ok {
+			return false
+		}
+
+		switch {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	} else {
+		x, ok := a.(string)
+		if !ok {
+			return false
+		}
+
+		y, ok := b.(string)
+		if !ok {
+			return false
+		}
+
+		cmp = strings.Compare(x, y)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+type exprTokenKind int
+
+const (
+	exprTokenEOF exprTokenKind = iota
+	exprTokenIdent
+	exprTokenNumber
+	exprTokenString
+	exprTokenOp
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	pos  int
+	// val contains the decoded value of number and string literals
+	val interface{}
+}
+
+func (t exprToken) String() string {
+	if t.kind == exprTokenEOF {
+		return "end of expression"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+type exprLexer struct {
+	src string
+	pos int
+}
+
+// exprOperators are ordered so that longer operators go first
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "-", "(", ")", "[", "]", ",", "."}
+
+func (l *exprLexer) next() (exprToken, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+
+	start := l.pos
+
+	if l.pos >= len(l.src) {
+		return exprToken{kind: exprTokenEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+
+	switch {
+	case c == '_' || c == '$' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || l.src[l.pos] == '$' || unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos]))) {
+			l.pos++
+		}
+
+		return exprToken{kind: exprTokenIdent, text: l.src[start:l.pos], pos: start}, nil
+	case unicode.IsDigit(rune(c)):
+		for l.pos < len(l.src) && (unicode.IsDigit(rune(l.src[l.pos])) || strings.ContainsRune(".eE", rune(l.src[l.pos])) ||
+			((l.src[l.pos] == '+' || l.src[l.pos] == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E'))) {
+			l.pos++
+		}
+
+		text := l.src[start:l.pos]
+		num, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return exprToken{}, fmt.Errorf("invalid number %q at position %d", text, start)
+		}
+
+		return exprToken{kind: exprTokenNumber, text: text, pos: start, val: num}, nil
+	case c == '\'' || c == '"':
+		return l.string(c)
+	}
+
+	for _, op := range exprOperators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return exprToken{kind: exprTokenOp, text: op, pos: start}, nil
+		}
+	}
+
+	return exprToken{}, fmt.Errorf("unexpected character %q at position %d", c, start)
+}
+
+func (l *exprLexer) string(quote byte) (exprToken, error) {
+	start := l.pos
+	l.pos++
+
+	var sb strings.Builder
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+
+		switch c {
+		case quote:
+			return exprToken{kind: exprTokenString, text: l.src[start:l.pos], pos: start, val: sb.String()}, nil
+		case '\\':
+			if l.pos >= len(l.src) {
+				break
+			}
+
+			esc := l.src[l.pos]
+			l.pos++
+
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(esc)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return exprToken{}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+// exprParser is a recursive descent parser; precedence (from lowest): ||, &&, equality, comparison and in, unary
+type exprParser struct {
+	lexer *exprLexer
+	tok   exprToken
+}
+
+func (p *exprParser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return fmt.Errorf("invalid where expression: %w", err)
+	}
+
+	p.tok = tok
+
+	return nil
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid where expression: %s at position %d", fmt.Sprintf(format, args...), p.tok.pos)
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	if p.tok.kind != exprTokenOp && !(p.tok.kind == exprTokenIdent && p.tok.text == "in") {
+		return false
+	}
+
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q, got %s", op, p.tok)
+	}
+
+	return p.advance()
+}
+
+func (p *exprParser) parseBinary(next func() (exprNode, error), ops ...string) (exprNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp(ops...) {
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &exprBinary{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseBinary(p.parseEquality, "&&")
+}
+
+func (p *exprParser) parseEquality() (exprNode, error) {
+	left, err := p.parseBinary(p.parseComparison, "==", "!=")
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isOp("=~", "!~") {
+		return left, nil
+	}
+
+	negate := p.tok.text == "!~"
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != exprTokenString {
+		return nil, p.errorf("regular expression must be a string literal")
+	}
+
+	re, err := regexp.Compile(p.tok.val.(string))
+	if err != nil {
+		return nil, p.errorf("invalid regular expression: %v", err)
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	return &exprRegexp{x: left, re: re, negate: negate}, nil
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	return p.parseBinary(p.parseUnary, "<", "<=", ">", ">=", "in")
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("!", "-") {
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &exprUnary{op: op, x: x}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.tok
+
+	switch tok.kind {
+	case exprTokenNumber, exprTokenString:
+		return &exprLiteral{tok.val}, p.advance()
+	case exprTokenIdent:
+		switch tok.text {
+		case "true":
+			return &exprLiteral{true}, p.advance()
+		case "false":
+			return &exprLiteral{false}, p.advance()
+		case "null":
+			return &exprLiteral{nil}, p.advance()
+		case "msg":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			return p.parsePath()
+		}
+
+		return nil, p.errorf("unknown identifier %s (message fields must be accessed via msg)", tok)
+	case exprTokenOp:
+		switch tok.text {
+		case "(":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			return node, p.expect(")")
+		case "[":
+			return p.parseArray()
+		}
+	}
+
+	return nil, p.errorf("unexpected %s", tok)
+}
+
+func (p *exprParser) parsePath() (exprNode, error) {
+	path := &exprPath{}
+
+	for {
+		switch {
+		case p.isOp("."):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			if p.tok.kind != exprTokenIdent {
+				return nil, p.errorf("expected field name, got %s", p.tok)
+			}
+
+			path.keys = append(path.keys, p.tok.text)
+
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case p.isOp("["):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			switch p.tok.kind {
+			case exprTokenString:
+				path.keys = append(path.keys, p.tok.val.(string))
+			case exprTokenNumber:
+				path.keys = append(path.keys, int(p.tok.val.(float64)))
+			default:
+				return nil, p.errorf("expected field name or index, got %s", p.tok)
+			}
+
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *exprParser) parseArray() (exprNode, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	arr := &exprArray{}
+
+	for !p.isOp("]") {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		arr.items = append(arr.items, item)
+
+		if !p.isOp(",") {
+			break
+		}
+
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	return arr, p.expect("]")
+}
@@ -0,0 +1,94 @@
+package cable
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExprMatcher(t *testing.T) {
+	msg := map[string]interface{}{
+		"type":    "chat",
+		"room_id": float64(2),
+		"text":    "Hello, world",
+		"data": map[string]interface{}{
+			"user":  map[string]interface{}{"id": int8(42), "name": "jack"},
+			"tags":  []interface{}{"a", "b"},
+			"items": []interface{}{map[string]interface{}{"id": uint16(1)}},
+		},
+		"content-type": "text",
+		"flag":         true,
+		"empty":        nil,
+	}
+
+	cases := map[string]bool{
+		`msg.type == 'chat' && msg.room_id in [1, 2]`:     true,
+		`msg.type == "chat" && msg.room_id in [1, 3]`:     false,
+		`msg.data.user.id == 42`:                          true,
+		`msg.data.user.id >= 42 && msg.data.user.id < 43`: true,
+		`msg.data.user.id > 42`:                           false,
+		`msg.data.items[0].id == 1`:                       true,
+		`msg.data.items[1].id == 1`:                       false,
+		`msg["content-type"] == "text"`:                   true,
+		`"a" in msg.data.tags`:                            true,
+		`"world" in msg.text`:                             true,
+		`"user" in msg.data`:                              true,
+		`msg.text =~ "^hello"`:                            false,
+		`msg.text =~ "(?i)^hello"`:                        true,
+		`msg.text !~ "bye"`:                               true,
+		`msg.missing == null && msg.empty == null`:        true,
+		`msg.missing.deeply.nested`:                       false,
+		`!msg.flag || (msg.type != "chat")`:               false,
+		`msg.flag && !(msg.room_id in [3])`:               true,
+		`msg.room_id == -2 || msg.room_id == 2.0`:         true,
+		`msg.data.user.name > "jill"`:                     false,
+		`msg.data.tags == ["a", "b"]`:                     true,
+		`msg.type > 1`:                                    false,
+	}
+
+	for source, expected := range cases {
+		m, err := NewExprMatcher(source)
+		require.NoError(t, err, source)
+		assert.Equal(t, expected, m.Match(msg), source)
+	}
+}
+
+func TestExprMatcherErrors(t *testing.T) {
+	cases := map[string]string{
+		`type == "chat"`:         `unknown identifier "type" (message fields must be accessed via msg) at position 0`,
+		`msg.type ==`:            `unexpected end of expression at position 11`,
+		`msg.type == 'chat`:      `unterminated string at position 12`,
+		`msg.text =~ msg.re`:     `regular expression must be a string literal at position 12`,
+		`msg.text =~ "("`:        `invalid regular expression`,
+		`(msg.type == "chat"`:    `expected ")", got end of expression at position 19`,
+		`msg.type == "chat" msg`: `unexpected "msg" at position 19`,
+		`msg.type # 1`:           `unexpected character '#' at position 9`,
+	}
+
+	for source, expected := range cases {
+		_, err := NewExprMatcher(source)
+		require.Error(t, err, source)
+		assert.Contains(t, err.Error(), expected, source)
+	}
+}
+
+func TestExprMatcherIsCached(t *testing.T) {
+	a, err := NewExprMatcher(`msg.n == 1`)
+	require.NoError(t, err)
+
+	b, err := NewExprMatcher(`msg.n == 1`)
+	require.NoError(t, err)
+
+	assert.Same(t, a, b)
+}
+
+func TestExprCacheIsBounded(t *testing.T) {
+	for i := 0; i < maxCompiledCacheSize*2; i++ {
+		_, err := NewExprMatcher(`msg.user_id == ` + strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+
+	assert.LessOrEqual(t, compiledExprs.len(), maxCompiledCacheSize)
+}
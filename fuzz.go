@@ -0,0 +1,465 @@
+package cable
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.k6.io/k6/metrics"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/anycable/xk6-cable/ac_protos"
+)
+
+const (
+	defaultFuzzIterations     = 100
+	defaultFuzzWaitMs         = 200
+	defaultFuzzOversizedBytes = 1024 * 1024
+	defaultFuzzChannel        = "EchoChannel"
+
+	fuzzOutcomeOK = "ok"
+
+	// fuzzUTF8Marker is replaced with invalid UTF-8 bytes (of the same length) after encoding
+	fuzzUTF8Marker  = "__fuzz__"
+	fuzzInvalidUTF8 = "\xff\xfe\xfd\xfc\xc3\x28\xa0\xa1"
+)
+
+var fuzzMutations = []string{
+	"field_drop",
+	"type_swap",
+	"oversized_string",
+	"bogus_identifier",
+	"unknown_command",
+	"invalid_utf8",
+	"truncated",
+}
+
+type fuzzCommand struct {
+	Command    string `json:"command"`
+	Identifier string `json:"identifier"`
+	Data       string `json:"data"`
+}
+
+type fuzzOptions struct {
+	Seed           *int64          `json:"seed"`
+	Iterations     int             `json:"iterations"`
+	Mutations      []string        `json:"mutations"`
+	Commands       []fuzzCommand   `json:"commands"`
+	Channel        string          `json:"channel"`
+	WaitMs         int             `json:"waitMs"`
+	OversizedBytes int             `json:"oversizedBytes"`
+	ConnectOptions json.RawMessage `json:"connectOptions"`
+}
+
+// FuzzReport contains the results of the fuzzing session
+type FuzzReport struct {
+	Seed        int64                         `js:"seed"`
+	Codec       string                        `js:"codec"`
+	Iterations  int                           `js:"iterations"`
+	Disconnects int                           `js:"disconnects"`
+	Drops       int                           `js:"drops"`
+	Mutations   map[string]*FuzzMutationStats `js:"mutations"`
+	Failures    []*FuzzFailure                `js:"failures"`
+	Error       string                        `js:"error"`
+}
+
+// FuzzMutationStats contains the outcomes of the particular mutation
+type FuzzMutationStats struct {
+	Sent        int `js:"sent"`
+	Disconnects int `js:"disconnects"`
+	Drops       int `js:"drops"`
+}
+
+// FuzzFailure describes the mutated frame which caused the server to close the connection
+type FuzzFailure struct {
+	Iteration int    `js:"iteration"`
+	Mutation  string `js:"mutation"`
+	Command   string `js:"command"`
+	Outcome   string `js:"outcome"`
+	// Payload is the frame contents (hex-encoded for binary codecs), truncated to 256 bytes
+	Payload string `js:"payload"`
+}
+
+// Fuzz sends mutated protocol commands to the server and records which mutations caused
+// the server to disconnect the client (via the disconnect message) or to drop the connection.
+// A new connection is established after each failure.
+func (c *Cable) Fuzz(cableUrl string, optsIn sobek.Value) (*FuzzReport, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, errCableInInitContext
+	}
+
+	var opts fuzzOptions
+	if err := decodeOptions(c.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	cOpts, err := decodeConnectOptions(opts.ConnectOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if pusher, _ := cOpts.pusher(); pusher {
+		return nil, fmt.Errorf("fuzzing is only supported for the Action Cable protocol")
+	}
+
+	f, err := newFuzzer(&opts, cOpts.codec())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FuzzReport{
+		Seed:       f.seed,
+		Codec:      codecName(cOpts.codec()),
+		Iterations: f.iterations,
+		Mutations:  make(map[string]*FuzzMutationStats),
+	}
+
+	for _, m := range f.mutations {
+		report.Mutations[m] = &FuzzMutationStats{}
+	}
+
+	logger := createLogger(state, cOpts)
+	wait := time.Duration(f.waitMs) * time.Millisecond
+
+	var client *Client
+
+	for i := 0; i < f.iterations; i++ {
+		if c.vu.Context().Err() != nil {
+			break
+		}
+
+		if client == nil {
+			client, err = c.connect(cableUrl, cOpts, logger)
+			if err != nil {
+				report.Error = err.Error()
+				report.Iterations = i
+				break
+			}
+		}
+
+		cmd := f.commands[f.rng.Intn(len(f.commands))]
+		mutation := f.mutations[f.rng.Intn(len(f.mutations))]
+
+		payload, err := f.mutate(mutation, cmd)
+		if err != nil {
+			return nil, err
+		}
+
+		outcome := fuzzOutcomeOK
+
+		if err := client.sendRaw(payload, f.codec.MessageType != JSONCodec.MessageType); err != nil {
+			outcome = closeReasonDrop
+		} else {
+			timer := time.NewTimer(wait)
+			select {
+			case <-client.closedCh:
+				outcome = client.closeReason
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+
+		stats := report.Mutations[mutation]
+		stats.Sent++
+
+		if outcome != fuzzOutcomeOK {
+			if outcome == closeReasonDisconnect {
+				stats.Disconnects++
+				report.Disconnects++
+			} else {
+				stats.Drops++
+				report.Drops++
+			}
+
+			report.Failures = append(report.Failures, &FuzzFailure{
+				Iteration: i,
+				Mutation:  mutation,
+				Command:   cmd.Command,
+				Outcome:   outcome,
+				Payload:   f.preview(payload),
+			})
+
+			client.Disconnect()
+			client = nil
+		}
+
+		c.trackFuzz(mutation, outcome, report.Codec)
+	}
+
+	if client != nil {
+		client.Disconnect()
+	}
+
+	return report, nil
+}
+
+func (c *Cable) trackFuzz(mutation string, outcome string, codec string) {
+	state := c.vu.State()
+
+	metrics.PushIfNotDone(c.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.FuzzMutations,
+			Tags:   state.Tags.GetCurrentValues().Tags.With("mutation", mutation).With("outcome", outcome).With("codec", codec),
+		},
+		Time:  time.Now(),
+		Value: 1,
+	})
+}
+
+func codecName(codec *Codec) string {
+	switch codec {
+	case MsgPackCodec:
+		return "msgpack"
+	case ProtobufCodec:
+		return "protobuf"
+	default:
+		return "json"
+	}
+}
+
+// fuzzer generates mutated commands using a seeded RNG
+type fuzzer struct {
+	seed           int64
+	rng            *rand.Rand
+	codec          *Codec
+	iterations     int
+	waitMs         int
+	oversizedBytes int
+	mutations      []string
+	commands       []fuzzCommand
+}
+
+func newFuzzer(opts *fuzzOptions, codec *Codec) (*fuzzer, error) {
+	f := &fuzzer{
+		codec:          codec,
+		iterations:     opts.Iterations,
+		waitMs:         opts.WaitMs,
+		oversizedBytes: opts.OversizedBytes,
+		mutations:      opts.Mutations,
+		commands:       opts.Commands,
+	}
+
+	if opts.Seed != nil {
+		f.seed = *opts.Seed
+	} else {
+		f.seed = time.Now().UnixNano()
+	}
+
+	f.rng = rand.New(rand.NewSource(f.seed))
+
+	if f.iterations <= 0 {
+		f.iterations = defaultFuzzIterations
+	}
+
+	if f.waitMs <= 0 {
+		f.waitMs = defaultFuzzWaitMs
+	}
+
+	if f.oversizedBytes <= 0 {
+		f.oversizedBytes = defaultFuzzOversizedBytes
+	}
+
+	if len(f.mutations) == 0 {
+		f.mutations = fuzzMutations
+	}
+
+	for _, m := range f.mutations {
+		if !isFuzzMutation(m) {
+			return nil, fmt.Errorf("unknown mutation: %s (supported mutations: %s)", m, strings.Join(fuzzMutations, ", "))
+		}
+	}
+
+	if len(f.commands) == 0 {
+		channel := opts.Channel
+		if channel == "" {
+			channel = defaultFuzzChannel
+		}
+
+		identifier, _ := json.Marshal(map[string]string{"channel": channel})
+
+		f.commands = []fuzzCommand{
+			{Command: "subscribe", Identifier: string(identifier)},
+			{Command: "message", Identifier: string(identifier), Data: `{"action":"echo","text":"hello"}`},
+		}
+	}
+
+	return f, nil
+}
+
+func isFuzzMutation(name string) bool {
+	for _, m := range fuzzMutations {
+		if m == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mutate applies the mutation to the command and returns the encoded frame payload
+func (f *fuzzer) mutate(mutation string, cmd fuzzCommand) ([]byte, error) {
+	fields := map[string]interface{}{
+		"command":    cmd.Command,
+		"identifier": cmd.Identifier,
+	}
+
+	if cmd.Data != "" {
+		fields["data"] = cmd.Data
+	}
+
+	switch mutation {
+	case "field_drop":
+		delete(fields, f.pickField(fields))
+	case "type_swap":
+		swaps := []interface{}{42, 3.14, true, nil, []interface{}{1, "two"}, map[string]interface{}{"nested": "object"}}
+		fields[f.pickField(fields)] = swaps[f.rng.Intn(len(swaps))]
+	case "oversized_string":
+		fields[f.pickField(fields)] = strings.Repeat("a", f.oversizedBytes)
+	case "bogus_identifier":
+		bogus := []string{
+			"",
+			"{not a json",
+			`{"channel":"NonExistentChannel"}`,
+			`{"channel":null}`,
+			"[]",
+			f.randomString(32),
+		}
+		fields["identifier"] = bogus[f.rng.Intn(len(bogus))]
+	case "unknown_command":
+		fields["command"] = f.randomString(8)
+	case "invalid_utf8":
+		key := f.pickField(fields)
+		if str, ok := fields[key].(string); ok && len(str) > 0 {
+			pos := f.rng.Intn(len(str))
+			fields[key] = str[:pos] + fuzzUTF8Marker + str[pos:]
+		} else {
+			fields[key] = fuzzUTF8Marker
+		}
+	}
+
+	payload, err := f.encode(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mutation {
+	case "invalid_utf8":
+		payload = bytes.Replace(payload, []byte(fuzzUTF8Marker), []byte(fuzzInvalidUTF8), 1)
+	case "truncated":
+		if len(payload) > 1 {
+			payload = payload[:1+f.rng.Intn(len(payload)-1)]
+		}
+	}
+
+	return payload, nil
+}
+
+// pickField returns a random field name (in a deterministic way)
+func (f *fuzzer) pickField(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys[f.rng.Intn(len(keys))]
+}
+
+func (f *fuzzer) randomString(n int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz_"
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[f.rng.Intn(len(letters))]
+	}
+
+	return string(b)
+}
+
+// encode encodes the (possibly invalid) command fields with the codec
+func (f *fuzzer) encode(fields map[string]interface{}) ([]byte, error) {
+	switch f.codec {
+	case MsgPackCodec:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetSortMapKeys(true)
+		if err := enc.Encode(fields); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case ProtobufCodec:
+		return encodeFuzzProtobuf(fields), nil
+	default:
+		return json.Marshal(fields)
+	}
+}
+
+// encodeFuzzProtobuf encodes fields as the action_cable.Message protobuf manually,
+// so that type swaps result in the wire type mismatches
+func encodeFuzzProtobuf(fields map[string]interface{}) []byte {
+	var b []byte
+
+	if cmd, ok := fields["command"]; ok {
+		if str, ok := cmd.(string); ok {
+			val, known := pb.Command_value[str]
+			if !known {
+				// Unknown enum value
+				val = 100 + int32(len(str))
+			}
+			b = protowire.AppendTag(b, 2, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(val))
+		} else {
+			b = appendFuzzProtobufMismatch(b, 2, protowire.BytesType)
+		}
+	}
+
+	for _, field := range []struct {
+		num protowire.Number
+		key string
+	}{{3, "identifier"}, {4, "data"}} {
+		num := field.num
+		val, ok := fields[field.key]
+		if !ok {
+			continue
+		}
+
+		if str, ok := val.(string); ok {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, str)
+		} else {
+			b = appendFuzzProtobufMismatch(b, num, protowire.VarintType)
+		}
+	}
+
+	return b
+}
+
+func appendFuzzProtobufMismatch(b []byte, num protowire.Number, typ protowire.Type) []byte {
+	b = protowire.AppendTag(b, num, typ)
+
+	if typ == protowire.VarintType {
+		return protowire.AppendVarint(b, 42)
+	}
+
+	return protowire.AppendBytes(b, []byte{0x2a})
+}
+
+func (f *fuzzer) preview(payload []byte) string {
+	if len(payload) > 256 {
+		payload = payload[:256]
+	}
+
+	if f.codec == JSONCodec {
+		return string(payload)
+	}
+
+	return hex.EncodeToString(payload)
+}
@@ -0,0 +1,79 @@
+package cable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzerIsDeterministic(t *testing.T) {
+	seed := int64(42)
+
+	generate := func() [][]byte {
+		f, err := newFuzzer(&fuzzOptions{Seed: &seed, OversizedBytes: 64}, MsgPackCodec)
+		require.NoError(t, err)
+
+		var payloads [][]byte
+		for i := 0; i < 50; i++ {
+			cmd := f.commands[f.rng.Intn(len(f.commands))]
+			mutation := f.mutations[f.rng.Intn(len(f.mutations))]
+
+			payload, err := f.mutate(mutation, cmd)
+			require.NoError(t, err)
+
+			payloads = append(payloads, payload)
+		}
+
+		return payloads
+	}
+
+	assert.Equal(t, generate(), generate())
+}
+
+func TestFuzzerUnknownMutation(t *testing.T) {
+	_, err := newFuzzer(&fuzzOptions{Mutations: []string{"explode"}}, JSONCodec)
+	assert.ErrorContains(t, err, "unknown mutation")
+}
+
+func TestFuzzerMutations(t *testing.T) {
+	seed := int64(1)
+	f, err := newFuzzer(&fuzzOptions{Seed: &seed}, JSONCodec)
+	require.NoError(t, err)
+
+	cmd := fuzzCommand{Command: "message", Identifier: `{"channel":"EchoChannel"}`, Data: `{"action":"echo"}`}
+
+	payload, err := f.mutate("invalid_utf8", cmd)
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), fuzzInvalidUTF8)
+
+	payload, err = f.mutate("unknown_command", cmd)
+	require.NoError(t, err)
+	assert.NotContains(t, string(payload), `"command":"message"`)
+
+	full, err := f.mutate("field_drop", fuzzCommand{Command: "subscribe", Identifier: "id"})
+	require.NoError(t, err)
+	assert.NotEqual(t, `{"command":"subscribe","identifier":"id"}`, string(full))
+}
+
+func TestFuzz(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	report := ts.run(t, `
+		cable.fuzz(CABLE_URL, { seed: 7, iterations: 20, waitMs: 10, oversizedBytes: 1024 })
+	`).Export().(*FuzzReport)
+
+	assert.Empty(t, report.Error)
+	assert.EqualValues(t, 7, report.Seed)
+	assert.Equal(t, 20, report.Iterations)
+
+	sent := 0
+	for _, stats := range report.Mutations {
+		sent += stats.Sent
+	}
+
+	assert.Equal(t, 20, sent)
+	// The mock server ignores invalid commands
+	assert.Zero(t, report.Disconnects+report.Drops)
+}
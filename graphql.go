@@ -0,0 +1,280 @@
+package cable
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+)
+
+const defaultGraphQLChannel = "GraphqlChannel"
+
+type graphqlOptions struct {
+	Channel       string `json:"channel"`
+	OperationName string `json:"operationName"`
+	TimeoutMs     int    `json:"timeoutMs"`
+	// TimestampField is the path to the publish time in the result data.
+	// It's required to track the update latency (graphql-ruby results carry no timestamps by themselves).
+	TimestampField string `json:"timestampField"`
+}
+
+// GraphQLSubscription represents a graphql-ruby subscription performed over GraphqlChannel
+type GraphQLSubscription struct {
+	channel *Channel
+	opts    *graphqlOptions
+
+	// pending contains the data from the initial execution result (if any)
+	pending []interface{}
+
+	completed bool
+	errors    []interface{}
+
+	// timestampWarned is set when the missing (or invalid) timestamp has been reported
+	timestampWarned bool
+}
+
+// GraphqlSubscribe subscribes to GraphqlChannel, executes the subscription query and waits for the initial result.
+func (c *Client) GraphqlSubscribe(query string, variablesIn sobek.Value, optsIn sobek.Value) (*GraphQLSubscription, error) {
+	var opts graphqlOptions
+	if err := decodeOptions(c.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	if opts.Channel == "" {
+		opts.Channel = defaultGraphQLChannel
+	}
+
+	variables, err := c.parseParams(variablesIn)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := c.recTimeout
+	if opts.TimeoutMs > 0 {
+		timeout = time.Duration(opts.TimeoutMs) * time.Millisecond
+	}
+
+	channelID, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	promise, err := c.subscribe(opts.Channel, map[string]interface{}{"channelId": channelID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := promise.Await(int(timeout.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}
+
+	if opts.OperationName != "" {
+		payload["operationName"] = opts.OperationName
+	}
+
+	if err := channel.perform("execute", payload); err != nil {
+		return nil, err
+	}
+
+	sub := &GraphQLSubscription{channel: channel, opts: &opts}
+
+	msg := sub.next(timeout)
+	if msg == nil {
+		return nil, fmt.Errorf("graphql subscription: timeout exceeded while waiting for the initial result")
+	}
+
+	sub.trackDuration(c.metrics.GraphQLSubscribeDuration, msg.receivedAt.Sub(start), msg.receivedAt)
+
+	if len(sub.errors) > 0 {
+		return nil, fmt.Errorf("graphql subscription failed: %v", sub.errors)
+	}
+
+	if data := graphqlData(msg.Message); !isEmptyData(data) {
+		sub.pending = append(sub.pending, data)
+	}
+
+	return sub, nil
+}
+
+// Receive returns the data of the next subscription update matching the condition (see Channel.Receive).
+// Returns null if no updates received in time or the subscription has been completed.
+func (s *GraphQLSubscription) Receive(cond sobek.Value) interface{} {
+	results := s.ReceiveN(1, cond)
+	if len(results) == 0 {
+		return nil
+	}
+
+	return results[0]
+}
+
+// ReceiveN returns the data of the provided number of subscription updates matching the condition
+func (s *GraphQLSubscription) ReceiveN(n int, cond sobek.Value) []interface{} {
+	var results []interface{}
+
+	matcher, err := s.channel.buildMatcher(cond)
+	if err != nil {
+		panic(err)
+	}
+
+	for len(s.pending) > 0 && len(results) < n {
+		data := s.pending[0]
+		s.pending = s.pending[1:]
+
+		if matcher.Match(data) {
+			results = append(results, data)
+		}
+	}
+
+	for len(results) < n && !s.completed {
+		msg := s.next(s.channel.client.recTimeout)
+		if msg == nil {
+			s.channel.logger.Warn("receive timeout exceeded; consider increasing receiveTimeoutMs configuration option")
+			return results
+		}
+
+		data := graphqlData(msg.Message)
+
+		s.trackLatency(data, msg.receivedAt)
+
+		if data == nil || !matcher.Match(data) {
+			continue
+		}
+
+		results = append(results, data)
+	}
+
+	return results
+}
+
+// Completed returns true if the server indicated that no more updates would be sent (`more: false`)
+func (s *GraphQLSubscription) Completed() bool {
+	return s.completed
+}
+
+// Errors returns GraphQL errors received so far
+func (s *GraphQLSubscription) Errors() []interface{} {
+	return s.errors
+}
+
+// next returns the next result message from the channel
+func (s *GraphQLSubscription) next(timeout time.Duration) *cableMsg {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		msg := s.channel.next(timer.C)
+		if msg == nil {
+			return nil
+		}
+
+		payload, ok := msg.Message.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		result, ok := payload["result"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if more, ok := payload["more"].(bool); ok && !more {
+			s.completed = true
+		}
+
+		if errors, ok := result["errors"].([]interface{}); ok {
+			s.errors = append(s.errors, errors...)
+		}
+
+		return msg
+	}
+}
+
+// trackLatency calculates the update latency using the timestamp from the data (if configured)
+func (s *GraphQLSubscription) trackLatency(data interface{}, receivedAt time.Time) {
+	if s.opts.TimestampField == "" || data == nil {
+		return
+	}
+
+	sentAt, ok := graphqlTimestamp(data, s.opts.TimestampField)
+	if !ok {
+		if !s.timestampWarned {
+			s.timestampWarned = true
+			s.channel.logger.Warnf("graphql update has no valid timestamp at %q; update latency is not tracked", s.opts.TimestampField)
+		}
+		return
+	}
+
+	s.trackDuration(s.channel.client.metrics.GraphQLUpdateLatency, receivedAt.Sub(sentAt), receivedAt)
+}
+
+func (s *GraphQLSubscription) trackDuration(metric *metrics.Metric, d time.Duration, when time.Time) {
+	c := s.channel.client
+	tags := c.sampleTags
+
+	if s.opts.OperationName != "" {
+		tags = tags.With("operation", s.opts.OperationName)
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   tags,
+		},
+		Time:  when,
+		Value: metrics.D(d),
+	})
+}
+
+// graphqlTimestamp returns the publish time stored at the path (either in milliseconds or in ISO 8601 format)
+func graphqlTimestamp(data interface{}, path string) (time.Time, bool) {
+	val, ok := lookupPath(data, path)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if v, ok := val.(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	}
+
+	ms, ok := toFloat64(val)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.UnixMilli(int64(ms)), true
+}
+
+// graphqlData extracts result.data from the GraphqlChannel message
+func graphqlData(msg interface{}) interface{} {
+	payload, ok := msg.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	result, ok := payload["result"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	return result["data"]
+}
+
+func isEmptyData(data interface{}) bool {
+	if data == nil {
+		return true
+	}
+
+	obj, ok := data.(map[string]interface{})
+
+	return ok && len(obj) == 0
+}
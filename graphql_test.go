@@ -0,0 +1,94 @@
+package cable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+func graphqlServerConfig(publishedAt interface{}) mockserver.Config {
+	update := func(title string, more bool) mockserver.Broadcast {
+		return mockserver.Broadcast{
+			Message: map[string]interface{}{
+				"result": map[string]interface{}{
+					"data": map[string]interface{}{
+						"postCreated": map[string]interface{}{"title": title, "createdAt": publishedAt},
+					},
+				},
+				"more": more,
+			},
+		}
+	}
+
+	initial := mockserver.Broadcast{
+		Delay:   50 * time.Millisecond,
+		Message: map[string]interface{}{"result": map[string]interface{}{"data": nil}, "more": true},
+	}
+
+	first := update("Hello", true)
+	first.Delay = 100 * time.Millisecond
+
+	last := update("Bye", false)
+	last.Delay = 150 * time.Millisecond
+
+	return mockserver.Config{
+		Channels: map[string]*mockserver.Channel{
+			"GraphqlChannel": {Echo: true, Broadcasts: []mockserver.Broadcast{initial, first, last}},
+		},
+	}
+}
+
+func TestGraphqlSubscribe(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, graphqlServerConfig(float64(time.Now().UnixMilli())))
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const subscription = client.graphqlSubscribe(
+			"subscription OnPost { postCreated { title createdAt } }",
+			{},
+			{ operationName: "OnPost", timestampField: "postCreated.createdAt" }
+		);
+
+		const titles = subscription.receiveN(2).map((data) => data.postCreated.title);
+
+		titles.join(",") + ":" + subscription.completed()
+	`)
+
+	assert.Equal(t, "Hello,Bye:true", val.String())
+
+	subscribe := ts.requireMetric(t, "cable_graphql_subscribe_duration", map[string]string{"operation": "OnPost"})
+	assert.Len(t, subscribe, 1)
+
+	latency := ts.requireMetric(t, "cable_graphql_update_latency", map[string]string{"operation": "OnPost"})
+	assert.Len(t, latency, 2)
+}
+
+func TestGraphqlSubscribeWithoutTimestamp(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, graphqlServerConfig("not a time"))
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const subscription = client.graphqlSubscribe("subscription { postCreated { title } }");
+
+		subscription.receive({ postCreated: { title: "Bye" } }).postCreated.title
+	`)
+
+	assert.Equal(t, "Bye", val.String())
+
+	ts.requireMetric(t, "cable_graphql_subscribe_duration", nil)
+
+	// The update latency requires the timestamp field
+	assert.Empty(t, ts.metricSamples("cable_graphql_update_latency", nil))
+
+	ts.run(t, `
+		const invalid = client.graphqlSubscribe("subscription { postCreated { title } }", {}, { timestampField: "postCreated.createdAt" });
+		invalid.receiveN(2);
+	`)
+
+	assert.Empty(t, ts.metricSamples("cable_graphql_update_latency", nil))
+}
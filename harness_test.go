@@ -0,0 +1,162 @@
+package cable
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/js/modulestest"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+// testState is the test harness for the JS bindings: it instantiates the cable module
+// within the k6 modulestest runtime (with a fake VU state) and collects emitted metric samples
+type testState struct {
+	*modulestest.Runtime
+
+	module  *CableModule
+	samples chan metrics.SampleContainer
+
+	mu        sync.Mutex
+	collected []metrics.Sample
+}
+
+// newTestState creates a runtime with the cable module exposed as the `cable` global
+// and moves it to the VU context
+func newTestState(t *testing.T) *testState {
+	t.Helper()
+
+	rt := modulestest.NewRuntime(t)
+	samples := make(chan metrics.SampleContainer, 10000)
+
+	m, ok := New().NewModuleInstance(rt.VU).(*CableModule)
+	require.True(t, ok)
+
+	require.NoError(t, rt.VU.Runtime().Set("cable", m.Exports().Default))
+
+	state := &lib.State{
+		Dialer:  &net.Dialer{},
+		Logger:  rt.VU.InitEnvField.Logger,
+		Samples: samples,
+		Options: lib.Options{
+			SystemTags: metrics.NewSystemTagSet(
+				metrics.TagURL,
+				metrics.TagStatus,
+				metrics.TagSubproto,
+				metrics.TagCheck,
+			),
+		},
+		BuiltinMetrics: rt.BuiltinMetrics,
+		Tags:           lib.NewVUStateTags(rt.VU.InitEnvField.Registry.RootTagSet()),
+	}
+
+	rt.MoveToVUContext(state)
+
+	return &testState{Runtime: rt, module: m, samples: samples}
+}
+
+// run evaluates the JS code and fails the test on error
+func (ts *testState) run(t *testing.T, code string) sobek.Value {
+	t.Helper()
+
+	val, err := ts.VU.Runtime().RunString(code)
+	require.NoError(t, err)
+
+	return val
+}
+
+// startMockServer starts the mock server and exposes its URL as the `CABLE_URL` global
+func (ts *testState) startMockServer(t *testing.T, config mockserver.Config) *mockserver.Server {
+	t.Helper()
+
+	server, err := mockserver.Start(config)
+	require.NoError(t, err)
+
+	t.Cleanup(server.Close)
+
+	require.NoError(t, ts.VU.Runtime().Set("CABLE_URL", server.URL))
+
+	return server
+}
+
+// metricSamples returns the samples of the metric emitted so far matching the tags (if provided)
+func (ts *testState) metricSamples(name string, tags map[string]string) []metrics.Sample {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+drain:
+	for {
+		select {
+		case container := <-ts.samples:
+			ts.collected = append(ts.collected, container.GetSamples()...)
+		default:
+			break drain
+		}
+	}
+
+	var result []metrics.Sample
+
+	for _, sample := range ts.collected {
+		if sample.Metric.Name != name || !sampleHasTags(sample, tags) {
+			continue
+		}
+
+		result = append(result, sample)
+	}
+
+	return result
+}
+
+// requireMetric waits for the samples of the metric matching the tags to be emitted and returns them.
+// Samples could be emitted asynchronously (e.g., by the receive loop), so we give them some time to arrive.
+func (ts *testState) requireMetric(t *testing.T, name string, tags map[string]string) []metrics.Sample {
+	t.Helper()
+
+	var result []metrics.Sample
+
+	if !assert.Eventually(t, func() bool {
+		result = ts.metricSamples(name, tags)
+		return len(result) > 0
+	}, time.Second, 10*time.Millisecond) {
+		t.Fatalf("no samples emitted for %s with tags %v", name, tags)
+	}
+
+	return result
+}
+
+// metricSum returns the sum of the metric samples values matching the tags
+func (ts *testState) metricSum(name string, tags map[string]string) float64 {
+	sum := 0.0
+
+	for _, sample := range ts.metricSamples(name, tags) {
+		sum += sample.Value
+	}
+
+	return sum
+}
+
+func sampleHasTags(sample metrics.Sample, tags map[string]string) bool {
+	for k, v := range tags {
+		if val, ok := sample.Tags.Get(k); !ok || val != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func echoServerConfig() mockserver.Config {
+	return mockserver.Config{
+		Channels: map[string]*mockserver.Channel{
+			"EchoChannel":   {Echo: true},
+			"RejectChannel": {Reject: true},
+		},
+	}
+}
@@ -0,0 +1,350 @@
+package cable
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/netext/httpext"
+)
+
+const (
+	longPollIDHeader = "X-Anycable-Poll-Id"
+
+	// minPollBackoff and maxPollBackoff limit the delay between polls returning no messages
+	// (so we don't flood the server if it responds to polls immediately)
+	minPollBackoff = 50 * time.Millisecond
+	maxPollBackoff = time.Second
+)
+
+// ConnectLongPolling connects to the AnyCable long-polling endpoint, creates and starts client, and returns it to the js.
+func (c *Cable) ConnectLongPolling(cableUrl string, opts sobek.Value) (*Client, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, errCableInInitContext
+	}
+
+	cOpts, err := parseOptions(c.vu.Runtime(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if cOpts.codec() != JSONCodec {
+		return nil, fmt.Errorf("long-polling transport only supports json codec")
+	}
+
+	u, err := httpext.NewURL(cableUrl, cableUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := cOpts.header()
+	setOrigin(headers, cableUrl)
+
+	logger := createLogger(state, cOpts)
+
+	ctx, cancel := context.WithCancel(c.vu.Context())
+
+	t := &longPollTransport{
+		ctx:      ctx,
+		cancel:   cancel,
+		state:    state,
+		url:      u,
+		header:   headers,
+		timeout:  cOpts.handshakeTimeout(),
+		received: make(chan struct{}, 1),
+		wake:     make(chan struct{}, 1),
+	}
+
+	client := c.newClient(t, cableUrl, headers, cOpts, logger, state.Tags.GetCurrentValues().Tags)
+
+	err = client.start()
+	if err != nil {
+		cancel()
+		logger.Errorf("failed to initialize Action Cable connection: %v", err)
+		return nil, nil
+	}
+
+	return client, nil
+}
+
+// longPollTransport exchanges messages with the server via HTTP POST requests:
+// each request contains a (possibly empty) JSON array of commands, and each response contains
+// incoming messages (either as a JSON array or as newline-delimited JSON).
+// Polls are performed by a background goroutine; commands are sent via separate requests (so they don't wait for the pending poll).
+// Requests are performed via k6 HTTP machinery, so HTTP metrics are collected automatically.
+type longPollTransport struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	state   *lib.State
+	url     httpext.URL
+	header  http.Header
+	timeout time.Duration
+
+	// sendMu serializes command requests (so commands are delivered in order)
+	sendMu sync.Mutex
+	// backoff is the delay before the next poll after empty responses (only used by the polling goroutine)
+	backoff time.Duration
+
+	pollerOnce sync.Once
+	// received is notified when messages are enqueued (or the poll failed)
+	received chan struct{}
+	// wake interrupts the poll backoff when a command has been sent
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pollID  string
+	pending [][]byte
+	err     error
+}
+
+var _ transport = (*longPollTransport)(nil)
+
+// Receive returns the next pending message or waits for more messages to be fetched
+func (t *longPollTransport) Receive(msg *cableMsg) (int, error) {
+	for {
+		if raw := t.shift(); raw != nil {
+			if err := json.Unmarshal(raw, msg); err != nil {
+				return len(raw), &decodeError{err}
+			}
+
+			return len(raw), nil
+		}
+
+		t.pollerOnce.Do(func() { go t.pollLoop() })
+
+		if err := t.takeError(); err != nil {
+			return 0, err
+		}
+
+		select {
+		case <-t.received:
+		case <-t.ctx.Done():
+			return 0, io.EOF
+		}
+	}
+}
+
+// pollLoop performs poll requests until the connection is closed or a request fails
+func (t *longPollTransport) pollLoop() {
+	for {
+		received, err := t.request(nil)
+		if err != nil {
+			t.fail(err)
+
+			// Responses that couldn't be decoded are skipped (if configured) by the receiver, so we keep polling
+			var derr *decodeError
+			if !errors.As(err, &derr) {
+				return
+			}
+		}
+
+		if received > 0 {
+			t.backoff = 0
+			continue
+		}
+
+		if err := t.wait(); err != nil {
+			t.fail(err)
+			return
+		}
+	}
+}
+
+// wait sleeps before the next poll (the delay grows exponentially while polls return no messages).
+// Sending a command resets the backoff (to fetch the messages caused by the command sooner).
+func (t *longPollTransport) wait() error {
+	t.backoff *= 2
+
+	if t.backoff < minPollBackoff {
+		t.backoff = minPollBackoff
+	}
+
+	if t.backoff > maxPollBackoff {
+		t.backoff = maxPollBackoff
+	}
+
+	timer := time.NewTimer(t.backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.wake:
+		t.backoff = 0
+		return nil
+	case <-t.ctx.Done():
+		return io.EOF
+	}
+}
+
+// Send performs a request with the command; messages from the response are queued
+func (t *longPollTransport) Send(msg *cableMsg) (int, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	t.sendMu.Lock()
+	_, err = t.request(body)
+	t.sendMu.Unlock()
+
+	notify(t.wake)
+
+	return len(body), err
+}
+
+func (t *longPollTransport) Close() error {
+	t.cancel()
+	return nil
+}
+
+func (t *longPollTransport) shift() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.pending) == 0 {
+		return nil
+	}
+
+	raw := t.pending[0]
+	t.pending = t.pending[1:]
+
+	return raw
+}
+
+// fail stores the poll error to be returned by the receiver
+func (t *longPollTransport) fail(err error) {
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+
+	notify(t.received)
+}
+
+func (t *longPollTransport) takeError() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.err
+	t.err = nil
+
+	return err
+}
+
+// notify signals the channel without blocking
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// request performs a request with the command (if any) and returns the number of received messages
+func (t *longPollTransport) request(command []byte) (int, error) {
+	if t.ctx.Err() != nil {
+		return 0, io.EOF
+	}
+
+	body := bytes.NewBufferString("[")
+	if command != nil {
+		body.Write(command)
+	}
+	body.WriteString("]")
+
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    t.url.GetURL(),
+		Header: t.header.Clone(),
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	t.mu.Lock()
+	if t.pollID != "" {
+		req.Header.Set(longPollIDHeader, t.pollID)
+	}
+	t.mu.Unlock()
+
+	tagsAndMeta := t.state.Tags.GetCurrentValues()
+
+	resp, err := httpext.MakeRequest(t.ctx, t.state, &httpext.ParsedHTTPRequest{
+		URL:          &t.url,
+		Req:          req,
+		Body:         body,
+		Timeout:      t.timeout,
+		Redirects:    t.state.Options.MaxRedirects,
+		ActiveJar:    t.state.CookieJar,
+		ResponseType: httpext.ResponseTypeText,
+		TagsAndMeta:  tagsAndMeta,
+	})
+	if err != nil {
+		if t.ctx.Err() != nil {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+
+	if resp.Status < 200 || resp.Status >= 300 {
+		return 0, fmt.Errorf("poll request failed with status: %d", resp.Status)
+	}
+
+	data, _ := resp.Body.(string)
+
+	t.mu.Lock()
+
+	if id := resp.Headers[longPollIDHeader]; id != "" {
+		t.pollID = id
+	}
+
+	count, err := t.enqueue(data)
+
+	t.mu.Unlock()
+
+	if count > 0 {
+		notify(t.received)
+	}
+
+	return count, err
+}
+
+// enqueue adds messages from the response body to the pending queue and returns the number of messages
+func (t *longPollTransport) enqueue(data string) (int, error) {
+	data = strings.TrimSpace(data)
+
+	if data == "" {
+		return 0, nil
+	}
+
+	if strings.HasPrefix(data, "[") {
+		var messages []json.RawMessage
+		if err := json.Unmarshal([]byte(data), &messages); err != nil {
+			return 0, &decodeError{err}
+		}
+
+		for _, msg := range messages {
+			t.pending = append(t.pending, msg)
+		}
+
+		return len(messages), nil
+	}
+
+	count := 0
+
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			t.pending = append(t.pending, []byte(line))
+			count++
+		}
+	}
+
+	return count, nil
+}
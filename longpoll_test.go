@@ -0,0 +1,193 @@
+package cable
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/lib"
+)
+
+// longPollServer is a minimal AnyCable long-polling stand-in: commands are answered in the same response,
+// empty polls are answered with no messages after the hold time (to check backoff).
+type longPollServer struct {
+	*httptest.Server
+
+	hold time.Duration
+
+	inflight    int32
+	maxInflight int32
+	emptyPolls  int32
+
+	mu      sync.Mutex
+	pollIDs []string
+}
+
+func startLongPollServer(t *testing.T) *longPollServer {
+	t.Helper()
+
+	s := &longPollServer{hold: 10 * time.Millisecond}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *longPollServer) handle(w http.ResponseWriter, r *http.Request) {
+	current := atomic.AddInt32(&s.inflight, 1)
+	defer atomic.AddInt32(&s.inflight, -1)
+
+	for {
+		max := atomic.LoadInt32(&s.maxInflight)
+		if current <= max || atomic.CompareAndSwapInt32(&s.maxInflight, max, current) {
+			break
+		}
+	}
+
+	var commands []cableMsg
+	if err := json.NewDecoder(r.Body).Decode(&commands); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	pollID := r.Header.Get(longPollIDHeader)
+
+	s.mu.Lock()
+	s.pollIDs = append(s.pollIDs, pollID)
+	s.mu.Unlock()
+
+	var messages []interface{}
+
+	if pollID == "" {
+		w.Header().Set(longPollIDHeader, "poll-42")
+		messages = append(messages, map[string]string{"type": "welcome"})
+	}
+
+	for _, cmd := range commands {
+		switch cmd.Command {
+		case "subscribe":
+			messages = append(messages, map[string]string{"type": "confirm_subscription", "identifier": cmd.Identifier})
+		case "message":
+			var data map[string]interface{}
+			_ = json.Unmarshal([]byte(cmd.Data), &data)
+			messages = append(messages, map[string]interface{}{"identifier": cmd.Identifier, "message": data})
+		}
+	}
+
+	if len(messages) == 0 {
+		atomic.AddInt32(&s.emptyPolls, 1)
+		time.Sleep(s.hold)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(messages)
+}
+
+func TestLongPolling(t *testing.T) {
+	ts := newTestState(t)
+	ts.VU.StateField.Transport = http.DefaultTransport
+	ts.VU.StateField.BufferPool = lib.NewBufferPool()
+
+	server := startLongPollServer(t)
+	require.NoError(t, ts.VU.Runtime().Set("LP_URL", server.URL+"/lp"))
+
+	val := ts.run(t, `
+		const client = cable.connectLongPolling(LP_URL);
+		const channel = client.subscribe("ChatChannel", { room_id: 42 });
+
+		channel.perform("speak", { text: "hello" });
+		channel.perform("speak", { text: "bye" });
+
+		channel.receive({ text: "bye" }).text
+	`)
+
+	assert.Equal(t, "bye", val.String())
+
+	time.Sleep(300 * time.Millisecond)
+
+	ts.run(t, `client.disconnect()`)
+
+	// Commands are sent while the poll is pending; requests reuse the poll ID
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.maxInflight))
+
+	server.mu.Lock()
+	assert.Empty(t, server.pollIDs[0])
+	for _, id := range server.pollIDs[1:] {
+		assert.Equal(t, "poll-42", id)
+	}
+	server.mu.Unlock()
+
+	// Empty polls are performed with backoff (50ms, 100ms, 200ms, ...)
+	assert.LessOrEqual(t, atomic.LoadInt32(&server.emptyPolls), int32(8))
+
+	reqs := ts.requireMetric(t, "http_reqs", map[string]string{"url": server.URL + "/lp", "status": "200"})
+	assert.GreaterOrEqual(t, len(reqs), 4)
+}
+
+func TestLongPollingCommandsDuringPoll(t *testing.T) {
+	ts := newTestState(t)
+	ts.VU.StateField.Transport = http.DefaultTransport
+	ts.VU.StateField.BufferPool = lib.NewBufferPool()
+
+	server := startLongPollServer(t)
+	server.hold = 2 * time.Second
+	require.NoError(t, ts.VU.Runtime().Set("LP_URL", server.URL+"/lp"))
+
+	start := time.Now()
+
+	val := ts.run(t, `
+		const client = cable.connectLongPolling(LP_URL);
+		const channel = client.subscribe("ChatChannel", { room_id: 42 });
+
+		channel.perform("speak", { text: "hello" });
+		channel.receive({ text: "hello" }).text
+	`)
+
+	// Neither commands nor their responses wait for the held poll
+	assert.Equal(t, "hello", val.String())
+	assert.Less(t, time.Since(start), time.Second)
+
+	ts.run(t, `client.disconnect()`)
+}
+
+func TestLongPollingEnqueue(t *testing.T) {
+	lp := &longPollTransport{}
+
+	count, err := lp.enqueue(`{"type":"welcome"}` + "\n\n" + `{"type":"ping"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = lp.enqueue(" ")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = lp.enqueue(`[{"type":`)
+
+	var decodeErr *decodeError
+	assert.ErrorAs(t, err, &decodeErr)
+
+	var msg cableMsg
+	_, err = lp.Receive(&msg)
+	require.NoError(t, err)
+	assert.Equal(t, "welcome", msg.Type)
+
+	_, err = lp.Receive(&msg)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", msg.Type)
+
+	// Malformed messages are reported as decode errors (to be skipped if skipInvalidFrames is set)
+	_, err = lp.enqueue(`{"type":`)
+	require.NoError(t, err)
+
+	_, err = lp.Receive(&msg)
+	assert.ErrorAs(t, err, &decodeErr)
+}
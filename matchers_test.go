@@ -0,0 +1,224 @@
+package cable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+func TestTurboMatcher(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, mockserver.Config{
+		Channels: map[string]*mockserver.Channel{
+			"Turbo::StreamsChannel": {
+				Broadcasts: []mockserver.Broadcast{
+					{Message: `<turbo-stream action="remove" target="message_1"></turbo-stream>`},
+					{Delay: 10 * time.Millisecond, Message: `<turbo-stream action="append" target="messages"><template><p>Hi</p></template></turbo-stream>`},
+				},
+			},
+		},
+	})
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("Turbo::StreamsChannel", { signed_stream_name: "chat" });
+
+		const msg = channel.receive(cable.turbo({ action: "append", target: "messages" }));
+		cable.parseTurboStream(msg)[0].action
+	`)
+
+	assert.Equal(t, "append", val.String())
+}
+
+func TestTurboAttributeMatch(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	// Objects with the turbo key are matched by attributes
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("speak", { turbo: { enabled: false } });
+		channel.perform("speak", { turbo: { enabled: true } });
+
+		channel.receive({ turbo: { enabled: true } }).turbo.enabled
+	`)
+
+	assert.True(t, val.ToBoolean())
+}
+
+func TestCableReadyMatcher(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, mockserver.Config{
+		Channels: map[string]*mockserver.Channel{
+			"UsersChannel": {
+				Broadcasts: []mockserver.Broadcast{
+					{Message: map[string]interface{}{
+						"cableReady": true,
+						"operations": []interface{}{map[string]interface{}{"operation": "innerHtml", "selector": "#title"}},
+					}},
+					{Delay: 10 * time.Millisecond, Message: map[string]interface{}{
+						"cableReady": true,
+						"operations": map[string]interface{}{
+							"morph": []interface{}{map[string]interface{}{"selector": "#users", "html": "<div></div>"}},
+						},
+					}},
+				},
+			},
+		},
+	})
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("UsersChannel");
+
+		const msg = channel.receive(cable.cableReady({ selector: "#title" }));
+		if (cable.parseCableReady(msg)[0].operation !== "innerHtml") throw new Error("unexpected message");
+
+		channel.receiveOperations({ operation: "morph", selector: "#users" }).map((op) => op.operation)
+	`).Export()
+
+	assert.Equal(t, []interface{}{"morph"}, val)
+
+	// Objects with the cableReady key are matched by attributes
+	ts.startMockServer(t, echoServerConfig())
+
+	matched := ts.run(t, `
+		const echo = cable.connect(CABLE_URL).subscribe("EchoChannel");
+
+		echo.perform("speak", { cableReady: { version: 4 } });
+		echo.perform("speak", { cableReady: { version: 5 } });
+
+		echo.receive({ cableReady: { version: 5 } }).cableReady.version
+	`)
+
+	assert.EqualValues(t, 5, matched.ToInteger())
+}
+
+func TestCableReadyOperations(t *testing.T) {
+	msg := map[string]interface{}{
+		"cableReady": true,
+		"operations": map[string]interface{}{
+			"remove":    []interface{}{map[string]interface{}{"selector": "#b"}},
+			"innerHtml": []interface{}{map[string]interface{}{"selector": "#a", "position": int8(1)}},
+		},
+	}
+
+	ops, ok := cableReadyOperations(msg)
+	assert.True(t, ok)
+	assert.Equal(t, []map[string]interface{}{
+		{"operation": "innerHtml", "selector": "#a", "position": int8(1)},
+		{"operation": "remove", "selector": "#b"},
+	}, ops)
+
+	// The message itself is not modified
+	assert.NotContains(t, msg["operations"].(map[string]interface{})["remove"].([]interface{})[0], "operation")
+
+	// Numbers are matched regardless of the decoded type (e.g., msgpack integers)
+	matcher := &CableReadyMatcher{expected: map[string]interface{}{"operation": "innerHtml", "position": float64(1)}}
+	assert.True(t, matcher.Match(msg))
+
+	matcher = &CableReadyMatcher{expected: map[string]interface{}{"position": map[string]interface{}{"$gt": float64(1)}}}
+	assert.False(t, matcher.Match(msg))
+}
+
+func TestAttrMatcherWithNestedObjects(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { user: { id: 1 }, text: "first" });
+		channel.perform("echo", { user: { id: 2 }, text: "second" });
+
+		channel.receive({ user: { id: 2 } }).text
+	`)
+
+	assert.Equal(t, "second", val.String())
+}
+
+func TestAttrMatcherPartialAndOperators(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { data: { user: { id: 1, name: "jack" }, tags: ["a"] }, text: "first" });
+		channel.perform("echo", { data: { user: { id: 2, name: "jill" }, tags: ["a", "b"] }, score: 10, text: "second" });
+		channel.perform("echo", { data: { user: { id: 3, name: "john" }, tags: ["b"] }, score: 50, text: "third" });
+
+		[
+			channel.receive({ data: { user: { id: 2 }, tags: ["b"] } }).text,
+			channel.receive({ "data.user.name": { $regex: "^jo" }, score: { $gt: 20 } }).text,
+		]
+	`).Export()
+
+	assert.Equal(t, []interface{}{"second", "third"}, val)
+}
+
+func TestExprMatcherReceive(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { type: "chat", room_id: 3, text: "first" });
+		channel.perform("echo", { type: "typing", room_id: 1, text: "second" });
+		channel.perform("echo", { type: "chat", room_id: 2, text: "third" });
+
+		channel.receive(cable.where("msg.type == 'chat' && msg.room_id in [1, 2]")).text
+	`)
+
+	assert.Equal(t, "third", val.String())
+
+	_, err := ts.VU.Runtime().RunString(`channel.receive(cable.where("msg.type =="))`)
+	assert.ErrorContains(t, err, "invalid where expression: unexpected end of expression at position 11")
+
+	// Objects with the where key are matched by attributes
+	val = ts.run(t, `
+		channel.perform("echo", { where: "msg.type == 'chat'", text: "fourth" });
+		channel.receive({ where: "msg.type == 'chat'" }).text
+	`)
+
+	assert.Equal(t, "fourth", val.String())
+}
+
+func TestLoopWithOnMessage(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+		channel.ignoreReads();
+
+		const received = [];
+		channel.onMessage((msg) => received.push(msg.n));
+
+		let iterations = 0;
+
+		client.loop(() => {
+			if (iterations++ === 0) {
+				channel.perform("echo", { n: 1 });
+				channel.perform("echo", { n: 2 });
+			}
+
+			return received.length >= 2;
+		});
+
+		[received, iterations > 0]
+	`).Export().([]interface{})
+
+	assert.EqualValues(t, []interface{}{int64(1), int64(2)}, val[0])
+	assert.Equal(t, true, val[1])
+}
@@ -0,0 +1,72 @@
+package cable
+
+import (
+	"bytes"
+	"net"
+	"sync/atomic"
+)
+
+var handshakeTerminator = []byte("\r\n\r\n")
+
+// meteredConn wraps net.Conn and counts the number of bytes transferred over the wire.
+// It's used to measure the size of compressed WebSocket messages.
+type meteredConn struct {
+	net.Conn
+
+	written int64
+
+	// framesRead is the number of bytes read after the HTTP handshake response (i.e., WebSocket frames)
+	framesRead int64
+	// handshakeTail contains the last bytes of the handshake response read so far (to find the terminator
+	// split between reads); it's only accessed by the reading goroutine
+	handshakeTail []byte
+	handshakeDone bool
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	if n > 0 {
+		atomic.AddInt64(&c.framesRead, int64(c.countFrameBytes(b[:n])))
+	}
+
+	return n, err
+}
+
+// countFrameBytes returns the number of bytes belonging to WebSocket frames (skipping the handshake response)
+func (c *meteredConn) countFrameBytes(b []byte) int {
+	if c.handshakeDone {
+		return len(b)
+	}
+
+	data := append(c.handshakeTail, b...)
+
+	idx := bytes.Index(data, handshakeTerminator)
+	if idx < 0 {
+		if len(data) > len(handshakeTerminator) {
+			data = data[len(data)-len(handshakeTerminator):]
+		}
+		c.handshakeTail = append([]byte(nil), data...)
+		return 0
+	}
+
+	c.handshakeDone = true
+	c.handshakeTail = nil
+
+	return len(data) - idx - len(handshakeTerminator)
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.written, int64(n))
+	return n, err
+}
+
+// frameBytesRead returns the number of bytes read after the handshake
+func (c *meteredConn) frameBytesRead() int64 {
+	return atomic.LoadInt64(&c.framesRead)
+}
+
+func (c *meteredConn) bytesWritten() int64 {
+	return atomic.LoadInt64(&c.written)
+}
@@ -0,0 +1,148 @@
+package cable
+
+import (
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/metrics"
+)
+
+// cableMetrics contains custom metrics emitted by the extension (in addition to the k6 built-in WebSocket metrics)
+type cableMetrics struct {
+	CompressedBytesSent      *metrics.Metric
+	CompressedDataReceived   *metrics.Metric
+	UncompressedBytesSent    *metrics.Metric
+	UncompressedDataReceived *metrics.Metric
+
+	MessageSizeSent     *metrics.Metric
+	MessageSizeReceived *metrics.Metric
+
+	GraphQLSubscribeDuration *metrics.Metric
+	GraphQLUpdateLatency     *metrics.Metric
+
+	ReflexDuration  *metrics.Metric
+	RequestDuration *metrics.Metric
+
+	FuzzMutations *metrics.Metric
+
+	ReplayDivergence *metrics.Metric
+	ReplayLag        *metrics.Metric
+
+	SchemaValidMessages   *metrics.Metric
+	SchemaInvalidMessages *metrics.Metric
+
+	SequenceInOrder    *metrics.Metric
+	SequenceOutOfOrder *metrics.Metric
+	SequenceDuplicates *metrics.Metric
+	SequenceMissing    *metrics.Metric
+
+	DeliveryRatio   *metrics.Metric
+	DeliveryLatency *metrics.Metric
+
+	BarrierWait *metrics.Metric
+
+	BroadcastDuration *metrics.Metric
+	BroadcastLatency  *metrics.Metric
+}
+
+func registerMetrics(vu modules.VU) (*cableMetrics, error) {
+	var err error
+	registry := vu.InitEnv().Registry
+	m := &cableMetrics{}
+
+	if m.CompressedBytesSent, err = registry.NewMetric("cable_compressed_bytes_sent", metrics.Trend, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.CompressedDataReceived, err = registry.NewMetric("cable_compressed_data_received", metrics.Counter, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.UncompressedBytesSent, err = registry.NewMetric("cable_uncompressed_bytes_sent", metrics.Trend, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.UncompressedDataReceived, err = registry.NewMetric("cable_uncompressed_data_received", metrics.Counter, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.MessageSizeSent, err = registry.NewMetric("cable_message_size_sent", metrics.Trend, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.MessageSizeReceived, err = registry.NewMetric("cable_message_size_received", metrics.Trend, metrics.Data); err != nil {
+		return nil, err
+	}
+
+	if m.GraphQLSubscribeDuration, err = registry.NewMetric("cable_graphql_subscribe_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.GraphQLUpdateLatency, err = registry.NewMetric("cable_graphql_update_latency", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.ReflexDuration, err = registry.NewMetric("cable_reflex_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.RequestDuration, err = registry.NewMetric("cable_request_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.FuzzMutations, err = registry.NewMetric("cable_fuzz_mutations", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.ReplayDivergence, err = registry.NewMetric("cable_replay_divergence", metrics.Rate); err != nil {
+		return nil, err
+	}
+
+	if m.ReplayLag, err = registry.NewMetric("cable_replay_lag", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.SchemaValidMessages, err = registry.NewMetric("cable_schema_valid_messages", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.SchemaInvalidMessages, err = registry.NewMetric("cable_schema_invalid_messages", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.SequenceInOrder, err = registry.NewMetric("cable_sequence_in_order", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.SequenceOutOfOrder, err = registry.NewMetric("cable_sequence_out_of_order", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.SequenceDuplicates, err = registry.NewMetric("cable_sequence_duplicates", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.SequenceMissing, err = registry.NewMetric("cable_sequence_missing", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if m.DeliveryRatio, err = registry.NewMetric("cable_delivery_ratio", metrics.Rate); err != nil {
+		return nil, err
+	}
+
+	if m.DeliveryLatency, err = registry.NewMetric("cable_delivery_latency", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.BarrierWait, err = registry.NewMetric("cable_barrier_wait", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.BroadcastDuration, err = registry.NewMetric("cable_broadcast_duration", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	if m.BroadcastLatency, err = registry.NewMetric("cable_broadcast_latency", metrics.Trend, metrics.Time); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
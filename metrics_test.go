@@ -0,0 +1,152 @@
+package cable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/metrics"
+)
+
+func TestConnectMetrics(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `cable.connect(CABLE_URL)`)
+
+	sessions := ts.requireMetric(t, "ws_sessions", map[string]string{"url": server.URL, "status": "101"})
+	assert.Len(t, sessions, 1)
+
+	connecting := ts.requireMetric(t, "ws_connecting", map[string]string{"subproto": "actioncable-v1-json"})
+	assert.Greater(t, connecting[0].Value, 0.0)
+
+	welcome := ts.requireMetric(t, "cable_message_size_received", map[string]string{"type": "welcome"})
+	assert.Greater(t, welcome[0].Value, 0.0)
+}
+
+func TestMessageMetrics(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { text: "hello" });
+		channel.receive();
+	`)
+
+	// subscribe + message
+	assert.EqualValues(t, 2, ts.metricSum("ws_msgs_sent", nil))
+
+	ts.requireMetric(t, "cable_message_size_sent", map[string]string{"channel": "EchoChannel", "type": "subscribe"})
+	sent := ts.requireMetric(t, "cable_message_size_sent", map[string]string{"channel": "EchoChannel", "type": "message"})
+	assert.Greater(t, sent[0].Value, float64(len(`{"action":"echo","text":"hello"}`)))
+
+	ts.requireMetric(t, "cable_message_size_received", map[string]string{"channel": "EchoChannel", "type": "confirm_subscription"})
+	ts.requireMetric(t, "cable_message_size_received", map[string]string{"channel": "EchoChannel", "type": "message"})
+
+	// confirmation + echo (the welcome message is not tracked); received messages are tracked asynchronously
+	assert.Eventually(t, func() bool {
+		return ts.metricSum("ws_msgs_received", nil) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCompressionMetrics(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL, { compression: "deflate" });
+		const channel = client.subscribe("EchoChannel");
+
+		channel.perform("echo", { text: "a".repeat(4096) });
+		channel.receive();
+	`)
+
+	compressed := ts.metricSum("cable_compressed_bytes_sent", nil)
+	uncompressed := ts.metricSum("cable_uncompressed_bytes_sent", nil)
+
+	assert.Greater(t, uncompressed, 4096.0)
+	assert.Less(t, compressed, uncompressed)
+
+	// Received data is tracked per connection (as counters)
+	assert.Empty(t, ts.metricSamples("cable_uncompressed_data_received", nil))
+
+	ts.run(t, `client.disconnect()`)
+
+	received := ts.requireMetric(t, "cable_uncompressed_data_received", nil)
+	require.Len(t, received, 1)
+	assert.Greater(t, received[0].Value, 4096.0)
+	assert.Equal(t, metrics.Counter, received[0].Metric.Type)
+
+	wire := ts.requireMetric(t, "cable_compressed_data_received", nil)
+	require.Len(t, wire, 1)
+	assert.Greater(t, wire[0].Value, 0.0)
+	assert.Less(t, wire[0].Value, received[0].Value)
+}
+
+func TestCompressionRefused(t *testing.T) {
+	ts := newTestState(t)
+
+	config := echoServerConfig()
+	config.DisableCompression = true
+	ts.startMockServer(t, config)
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL, { compression: "deflate" });
+		const channel = client.subscribe("EchoChannel");
+		channel.perform("echo", { text: "hello" });
+
+		[client.compressionEnabled(), channel.receive().text]
+	`).Export().([]interface{})
+
+	assert.Equal(t, false, val[0])
+	assert.Equal(t, "hello", val[1])
+
+	ts.run(t, `client.disconnect()`)
+
+	assert.Empty(t, ts.metricSamples("cable_compressed_bytes_sent", nil))
+	assert.Empty(t, ts.metricSamples("cable_compressed_data_received", nil))
+}
+
+func TestMeteredConnSkipsHandshake(t *testing.T) {
+	conn := &meteredConn{}
+
+	assert.Equal(t, 0, conn.countFrameBytes([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r")))
+	assert.Equal(t, 3, conn.countFrameBytes([]byte("\n\r\nabc")))
+	assert.Equal(t, 4, conn.countFrameBytes([]byte("\r\n\r\n")))
+}
+
+func TestRequestMetrics(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel");
+
+		channel.request("echo", { text: "ping" });
+	`)
+
+	samples := ts.requireMetric(t, "cable_request_duration", map[string]string{"action": "echo"})
+	assert.Len(t, samples, 1)
+	assert.GreaterOrEqual(t, samples[0].Value, 0.0)
+}
+
+func TestRawFramesMetrics(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		client.sendRaw('{"command":"subscribe","identifier":"{\\"channel\\":\\"EchoChannel\\"}"}');
+		client.receiveRaw(1000)
+	`)
+
+	assert.Contains(t, val.String(), "confirm_subscription")
+
+	samples := ts.requireMetric(t, "cable_message_size_sent", map[string]string{"type": "raw"})
+	assert.Len(t, samples, 1)
+}
@@ -0,0 +1,138 @@
+package cable
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/sobek"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+const defaultMockPingIntervalMs = 3000
+
+type mockServerOptions struct {
+	Channels       map[string]*mockChannelOptions `json:"channels"`
+	PingIntervalMs *int                           `json:"pingIntervalMs"`
+	WelcomeDelayMs int                            `json:"welcomeDelayMs"`
+	RequireCookie  string                         `json:"requireCookie"`
+	MaxCommands    int                            `json:"maxCommands"`
+}
+
+type mockChannelOptions struct {
+	Echo           bool                    `json:"echo"`
+	Reject         bool                    `json:"reject"`
+	ConfirmDelayMs int                     `json:"confirmDelayMs"`
+	EchoDelayMs    int                     `json:"echoDelayMs"`
+	Broadcasts     []*mockBroadcastOptions `json:"broadcasts"`
+}
+
+type mockBroadcastOptions struct {
+	DelayMs int         `json:"delayMs"`
+	Message interface{} `json:"message"`
+}
+
+// MockServer is a local Action Cable server running within the k6 process
+type MockServer struct {
+	URL string `js:"url"`
+
+	server *mockserver.Server
+}
+
+// MockServer starts a local Action Cable server (supporting all the codecs) to develop scripts without a real server.
+// By default, EchoChannel and BenchmarkChannel echo performed actions back and RejectChannel rejects subscriptions.
+// The server is stopped when the VU context is done (or explicitly via `server.close()`).
+func (c *Cable) MockServer(optsIn sobek.Value) (*MockServer, error) {
+	var opts mockServerOptions
+	if err := decodeOptions(c.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	server, err := mockserver.Start(opts.config())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.vu.State() != nil {
+		go func() {
+			<-c.vu.Context().Done()
+			server.Close()
+		}()
+	}
+
+	return &MockServer{URL: server.URL, server: server}, nil
+}
+
+func (o *mockServerOptions) config() mockserver.Config {
+	config := mockserver.Config{
+		Channels:     make(map[string]*mockserver.Channel),
+		PingInterval: defaultMockPingIntervalMs * time.Millisecond,
+		WelcomeDelay: time.Duration(o.WelcomeDelayMs) * time.Millisecond,
+		MaxCommands:  o.MaxCommands,
+	}
+
+	if o.PingIntervalMs != nil {
+		config.PingInterval = time.Duration(*o.PingIntervalMs) * time.Millisecond
+	}
+
+	if o.RequireCookie != "" {
+		cookie := o.RequireCookie
+		config.Authenticate = func(r *http.Request) bool {
+			_, err := r.Cookie(cookie)
+			return err == nil
+		}
+	}
+
+	if o.Channels == nil {
+		config.Channels["EchoChannel"] = &mockserver.Channel{Echo: true}
+		config.Channels["BenchmarkChannel"] = &mockserver.Channel{Echo: true}
+		config.Channels["RejectChannel"] = &mockserver.Channel{Reject: true}
+
+		return config
+	}
+
+	for name, ch := range o.Channels {
+		channel := &mockserver.Channel{
+			Echo:         ch.Echo,
+			Reject:       ch.Reject,
+			ConfirmDelay: time.Duration(ch.ConfirmDelayMs) * time.Millisecond,
+			EchoDelay:    time.Duration(ch.EchoDelayMs) * time.Millisecond,
+		}
+
+		for _, b := range ch.Broadcasts {
+			channel.Broadcasts = append(channel.Broadcasts, mockserver.Broadcast{
+				Delay:   time.Duration(b.DelayMs) * time.Millisecond,
+				Message: b.Message,
+			})
+		}
+
+		config.Channels[name] = channel
+	}
+
+	return config
+}
+
+// Broadcast sends the message to all the subscribers of the channel and returns the number of recipients
+func (s *MockServer) Broadcast(channel string, message sobek.Value) int {
+	return s.server.Broadcast(channel, message.Export())
+}
+
+// Disconnect sends the disconnect message to all the clients and closes the connections
+func (s *MockServer) Disconnect(reason string, reconnect bool) {
+	s.server.Disconnect(reason, reconnect)
+}
+
+// Commands returns the most recent commands received by the server (up to the maxCommands option)
+func (s *MockServer) Commands() []*mockserver.Command {
+	return s.server.Commands()
+}
+
+// ConnectionsCount returns the number of active connections
+func (s *MockServer) ConnectionsCount() int {
+	return s.server.ConnectionsCount()
+}
+
+// Close stops the server
+func (s *MockServer) Close() {
+	s.server.Close()
+}
@@ -0,0 +1,84 @@
+package cable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockServerDefaults(t *testing.T) {
+	ts := newTestState(t)
+
+	val := ts.run(t, `
+		const server = cable.mockServer();
+		const client = cable.connect(server.url, { codec: "msgpack" });
+		const channel = client.subscribe("BenchmarkChannel");
+
+		channel.perform("echo", { text: "hello" });
+		const echo = channel.receive();
+
+		let rejected = false;
+		try {
+			client.subscribe("RejectChannel");
+		} catch (e) {
+			rejected = true;
+		}
+
+		server.close();
+
+		[echo.text, rejected, server.commands().length]
+	`).Export().([]interface{})
+
+	assert.Equal(t, "hello", val[0])
+	assert.Equal(t, true, val[1])
+	assert.EqualValues(t, 3, val[2])
+}
+
+func TestMockServerScriptedBroadcasts(t *testing.T) {
+	ts := newTestState(t)
+
+	val := ts.run(t, `
+		const server = cable.mockServer({
+			pingIntervalMs: 0,
+			channels: {
+				NewsChannel: {
+					confirmDelayMs: 10,
+					broadcasts: [{ delayMs: 10, message: { title: "scripted" } }],
+				},
+			},
+		});
+
+		const client = cable.connect(server.url);
+		const channel = client.subscribe("NewsChannel");
+		const scripted = channel.receive();
+
+		const recipients = server.broadcast("NewsChannel", { title: "manual" });
+
+		[scripted.title, recipients, channel.receive().title, server.connectionsCount()]
+	`).Export().([]interface{})
+
+	assert.Equal(t, "scripted", val[0])
+	assert.EqualValues(t, 1, val[1])
+	assert.Equal(t, "manual", val[2])
+	assert.EqualValues(t, 1, val[3])
+}
+
+func TestMockServerRequireCookie(t *testing.T) {
+	ts := newTestState(t)
+
+	val := ts.run(t, `
+		const server = cable.mockServer({ requireCookie: "user_id" });
+
+		[cable.connect(server.url) === null, cable.connect(server.url, { cookies: "user_id=1" }) !== null]
+	`).Export()
+
+	assert.Equal(t, []interface{}{true, true}, val)
+}
+
+func TestMockServerUnknownOption(t *testing.T) {
+	ts := newTestState(t)
+
+	_, err := ts.VU.Runtime().RunString(`cable.mockServer({ unknown: true })`)
+	require.Error(t, err)
+}
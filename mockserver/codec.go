@@ -0,0 +1,133 @@
+package mockserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+
+	pb "github.com/anycable/xk6-cable/ac_protos"
+)
+
+// Subprotocols supported by the server (the first one is used by default)
+var Subprotocols = []string{
+	"actioncable-v1-json",
+	"actioncable-v1-msgpack",
+	"actioncable-v1-protobuf",
+}
+
+// Message is the server-to-client message
+type Message struct {
+	Type       string      `json:"type,omitempty" msgpack:"type,omitempty"`
+	Identifier string      `json:"identifier,omitempty" msgpack:"identifier,omitempty"`
+	Message    interface{} `json:"message,omitempty" msgpack:"message,omitempty"`
+	SID        string      `json:"sid,omitempty" msgpack:"sid,omitempty"`
+	Reason     string      `json:"reason,omitempty" msgpack:"reason,omitempty"`
+	Reconnect  *bool       `json:"reconnect,omitempty" msgpack:"reconnect,omitempty"`
+}
+
+// Command is the client-to-server message
+type Command struct {
+	Command    string `json:"command" msgpack:"command"`
+	Identifier string `json:"identifier" msgpack:"identifier"`
+	Data       string `json:"data,omitempty" msgpack:"data,omitempty"`
+}
+
+// codec encodes messages and decodes commands for the negotiated subprotocol
+type codec interface {
+	encode(msg *Message) ([]byte, error)
+	decode(raw []byte) (*Command, error)
+	messageType() int
+}
+
+func codecFor(subprotocol string) codec {
+	switch subprotocol {
+	case "actioncable-v1-msgpack":
+		return msgpackCodec{}
+	case "actioncable-v1-protobuf":
+		return protobufCodec{}
+	default:
+		return jsonCodec{}
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) encode(msg *Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (jsonCodec) decode(raw []byte) (*Command, error) {
+	var cmd Command
+	if err := json.Unmarshal(raw, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+func (jsonCodec) messageType() int {
+	return websocket.TextMessage
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) encode(msg *Message) ([]byte, error) {
+	return msgpack.Marshal(msg)
+}
+
+func (msgpackCodec) decode(raw []byte) (*Command, error) {
+	var cmd Command
+	if err := msgpack.NewDecoder(bytes.NewReader(raw)).Decode(&cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+func (msgpackCodec) messageType() int {
+	return websocket.BinaryMessage
+}
+
+// protobufCodec uses the AnyCable protobuf schema; message payloads are encoded with msgpack
+type protobufCodec struct{}
+
+func (protobufCodec) encode(msg *Message) ([]byte, error) {
+	buf := &pb.Message{
+		Type:       pb.Type(pb.Type_value[msg.Type]),
+		Identifier: msg.Identifier,
+		Reason:     msg.Reason,
+	}
+
+	if msg.Reconnect != nil {
+		buf.Reconnect = *msg.Reconnect
+	}
+
+	if msg.Message != nil {
+		payload, err := msgpack.Marshal(msg.Message)
+		if err != nil {
+			return nil, err
+		}
+		buf.Message = payload
+	}
+
+	return proto.Marshal(buf)
+}
+
+func (protobufCodec) decode(raw []byte) (*Command, error) {
+	buf := &pb.Message{}
+	if err := proto.Unmarshal(raw, buf); err != nil {
+		return nil, err
+	}
+
+	if buf.Command == pb.Command_unknown_command {
+		return nil, fmt.Errorf("unknown command")
+	}
+
+	return &Command{Command: buf.Command.String(), Identifier: buf.Identifier, Data: buf.Data}, nil
+}
+
+func (protobufCodec) messageType() int {
+	return websocket.BinaryMessage
+}
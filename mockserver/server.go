@@ -0,0 +1,499 @@
+// Package mockserver implements a configurable in-process Action Cable server.
+// It's used for script development (via `cable.mockServer()`) and in tests.
+package mockserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Config describes the server behaviour
+type Config struct {
+	// Channels contains channels configuration by channel name.
+	// Subscriptions to unknown channels are rejected.
+	Channels map[string]*Channel
+	// PingInterval is the interval between pings (pings are disabled if zero)
+	PingInterval time.Duration
+	// WelcomeDelay is the delay before sending the welcome message
+	WelcomeDelay time.Duration
+	// Authenticate is called for each connection request; rejected connections
+	// receive the disconnect message (with the "unauthorized" reason) and are closed
+	Authenticate func(r *http.Request) bool
+	// DisableCompression makes the server refuse the permessage-deflate extension
+	DisableCompression bool
+	// MaxCommands is the number of the most recent commands kept by the server (DefaultMaxCommands if zero)
+	MaxCommands int
+}
+
+// DefaultMaxCommands is the default number of the most recent received commands kept by the server
+const DefaultMaxCommands = 1000
+
+// Channel describes the channel behaviour
+type Channel struct {
+	// Echo makes the channel transmit performed actions data back to the client.
+	// The "broadcast" action is broadcasted to all the subscribers of the same identifier instead.
+	Echo bool
+	// Reject makes the channel reject all subscriptions
+	Reject bool
+	// ConfirmDelay is the delay before confirming (or rejecting) the subscription
+	ConfirmDelay time.Duration
+	// EchoDelay is the delay before transmitting the echo response
+	EchoDelay time.Duration
+	// Broadcasts are sent to each subscriber after the subscription is confirmed
+	Broadcasts []Broadcast
+}
+
+// Broadcast is a scripted message sent to the subscriber
+type Broadcast struct {
+	// Delay is the delay after the subscription confirmation
+	Delay   time.Duration
+	Message interface{}
+}
+
+// Server is the in-process Action Cable server
+type Server struct {
+	// URL is the WebSocket URL of the server (e.g., ws://127.0.0.1:53421/cable)
+	URL string
+
+	config   Config
+	listener net.Listener
+	http     *http.Server
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	// commands is the ring buffer of the most recent commands (starting at commandsStart)
+	commands      []*Command
+	commandsStart int
+	closed        bool
+}
+
+// Start starts the server listening on a random local port
+func Start(config Config) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:      "ws://" + listener.Addr().String() + "/cable",
+		config:   config,
+		listener: listener,
+		sessions: make(map[*session]struct{}),
+		upgrader: websocket.Upgrader{
+			Subprotocols:      Subprotocols,
+			EnableCompression: !config.DisableCompression,
+			CheckOrigin:       func(r *http.Request) bool { return true },
+		},
+	}
+
+	s.http = &http.Server{Handler: s, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		_ = s.http.Serve(listener)
+	}()
+
+	return s, nil
+}
+
+// Close stops the server and closes all the connections
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	_ = s.http.Close()
+
+	for _, sess := range sessions {
+		sess.close()
+	}
+}
+
+// Broadcast sends the message to all the subscribers of the channel.
+// Returns the number of recipients.
+func (s *Server) Broadcast(channel string, message interface{}) int {
+	return s.broadcast(func(identifier, name string) bool { return name == channel }, message)
+}
+
+// BroadcastTo sends the message to all the subscribers of the identifier.
+// Returns the number of recipients.
+func (s *Server) BroadcastTo(identifier string, message interface{}) int {
+	return s.broadcast(func(id, _ string) bool { return id == identifier }, message)
+}
+
+func (s *Server) broadcast(match func(identifier, channel string) bool, message interface{}) int {
+	count := 0
+
+	for _, sess := range s.activeSessions() {
+		for identifier, channel := range sess.subscriptions() {
+			if match(identifier, channel) {
+				if sess.transmit(&Message{Identifier: identifier, Message: message}) == nil {
+					count++
+				}
+			}
+		}
+	}
+
+	return count
+}
+
+// Disconnect sends the disconnect message to all the clients and closes the connections
+func (s *Server) Disconnect(reason string, reconnect bool) {
+	for _, sess := range s.activeSessions() {
+		_ = sess.transmit(&Message{Type: "disconnect", Reason: reason, Reconnect: &reconnect})
+		sess.close()
+	}
+}
+
+// Commands returns the most recent commands received by the server (up to Config.MaxCommands) in the order of arrival
+func (s *Server) Commands() []*Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	commands := make([]*Command, 0, len(s.commands))
+	commands = append(commands, s.commands[s.commandsStart:]...)
+
+	return append(commands, s.commands[:s.commandsStart]...)
+}
+
+// ConnectionsCount returns the number of active connections
+func (s *Server) ConnectionsCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.sessions)
+}
+
+func (s *Server) activeSessions() []*session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+
+	return sessions
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	sess := &session{
+		server: s,
+		conn:   conn,
+		codec:  codecFor(conn.Subprotocol()),
+		subs:   make(map[string]string),
+		done:   make(chan struct{}),
+	}
+
+	defer sess.close()
+
+	if s.config.Authenticate != nil && !s.config.Authenticate(r) {
+		reconnect := false
+		_ = sess.transmit(&Message{Type: "disconnect", Reason: "unauthorized", Reconnect: &reconnect})
+		return
+	}
+
+	if !s.register(sess) {
+		return
+	}
+	defer s.unregister(sess)
+
+	if !sess.sleep(s.config.WelcomeDelay) {
+		return
+	}
+
+	if err := sess.transmit(&Message{Type: "welcome", SID: randomSID()}); err != nil {
+		return
+	}
+
+	if s.config.PingInterval > 0 {
+		go sess.pingLoop(s.config.PingInterval)
+	}
+
+	sess.readLoop()
+}
+
+func (s *Server) register(sess *session) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.sessions[sess] = struct{}{}
+	return true
+}
+
+func (s *Server) unregister(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sess)
+}
+
+func (s *Server) recordCommand(cmd *Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := s.config.MaxCommands
+	if limit <= 0 {
+		limit = DefaultMaxCommands
+	}
+
+	if len(s.commands) < limit {
+		s.commands = append(s.commands, cmd)
+		return
+	}
+
+	s.commands[s.commandsStart] = cmd
+	s.commandsStart = (s.commandsStart + 1) % len(s.commands)
+}
+
+// session represents a client connection
+type session struct {
+	server *Server
+	conn   *websocket.Conn
+	codec  codec
+
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string]string
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+var errSessionClosed = errors.New("session closed")
+
+func (s *session) readLoop() {
+	for {
+		_, raw, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		cmd, err := s.codec.decode(raw)
+		if err != nil {
+			// Invalid commands are ignored
+			continue
+		}
+
+		s.server.recordCommand(cmd)
+		s.handle(cmd)
+	}
+}
+
+func (s *session) handle(cmd *Command) {
+	switch cmd.Command {
+	case "subscribe":
+		s.subscribe(cmd.Identifier)
+	case "unsubscribe":
+		s.mu.Lock()
+		delete(s.subs, cmd.Identifier)
+		s.mu.Unlock()
+	case "message":
+		s.perform(cmd.Identifier, cmd.Data)
+	}
+}
+
+func (s *session) subscribe(identifier string) {
+	name := channelName(identifier)
+	channel := s.server.config.Channels[name]
+
+	if channel != nil && !s.sleep(channel.ConfirmDelay) {
+		return
+	}
+
+	if channel == nil || channel.Reject {
+		_ = s.transmit(&Message{Type: "reject_subscription", Identifier: identifier})
+		return
+	}
+
+	s.mu.Lock()
+	_, subscribed := s.subs[identifier]
+	if !subscribed {
+		s.subs[identifier] = name
+	}
+	s.mu.Unlock()
+
+	// Duplicate subscriptions are ignored (like Action Cable does)
+	if subscribed {
+		return
+	}
+
+	if err := s.transmit(&Message{Type: "confirm_subscription", Identifier: identifier}); err != nil {
+		return
+	}
+
+	if len(channel.Broadcasts) > 0 {
+		go s.scriptedBroadcasts(identifier, channel.Broadcasts)
+	}
+}
+
+func (s *session) perform(identifier string, data string) {
+	s.mu.Lock()
+	name, subscribed := s.subs[identifier]
+	s.mu.Unlock()
+
+	if !subscribed {
+		return
+	}
+
+	channel := s.server.config.Channels[name]
+	if channel == nil || !channel.Echo {
+		return
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		return
+	}
+
+	if !s.sleep(channel.EchoDelay) {
+		return
+	}
+
+	if payload["action"] == "broadcast" {
+		s.server.BroadcastTo(identifier, payload)
+		return
+	}
+
+	_ = s.transmit(&Message{Identifier: identifier, Message: payload})
+}
+
+func (s *session) scriptedBroadcasts(identifier string, broadcasts []Broadcast) {
+	start := time.Now()
+
+	for _, b := range broadcasts {
+		if !s.sleep(time.Until(start.Add(b.Delay))) {
+			return
+		}
+
+		s.mu.Lock()
+		_, subscribed := s.subs[identifier]
+		s.mu.Unlock()
+
+		if !subscribed {
+			return
+		}
+
+		if err := s.transmit(&Message{Identifier: identifier, Message: b.Message}); err != nil {
+			return
+		}
+	}
+}
+
+func (s *session) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.transmit(&Message{Type: "ping", Message: time.Now().Unix()}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *session) subscriptions() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := make(map[string]string, len(s.subs))
+	for k, v := range s.subs {
+		subs[k] = v
+	}
+
+	return subs
+}
+
+func (s *session) transmit(msg *Message) error {
+	b, err := s.codec.encode(msg)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	select {
+	case <-s.done:
+		return errSessionClosed
+	default:
+	}
+
+	return s.conn.WriteMessage(s.codec.messageType(), b)
+}
+
+// sleep waits for the specified duration; returns false if the session has been closed in the meantime
+func (s *session) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.writeMu.Lock()
+		defer s.writeMu.Unlock()
+
+		_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = s.conn.Close()
+	})
+}
+
+func channelName(identifier string) string {
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(identifier), &params); err != nil {
+		return ""
+	}
+
+	name, _ := params["channel"].(string)
+	return name
+}
+
+func randomSID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
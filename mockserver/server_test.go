@@ -0,0 +1,334 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+
+	pb "github.com/anycable/xk6-cable/ac_protos"
+)
+
+type testClient struct {
+	t     *testing.T
+	conn  *websocket.Conn
+	codec codec
+}
+
+func dial(t *testing.T, s *Server, subprotocol string, header http.Header) *testClient {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+
+	conn, _, err := dialer.Dial(s.URL, header)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testClient{t: t, conn: conn, codec: codecFor(subprotocol)}
+}
+
+// receive decodes the next message (using the client-side representation of the codecs)
+func (c *testClient) receive() *Message {
+	c.t.Helper()
+
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+	_, raw, err := c.conn.ReadMessage()
+	require.NoError(c.t, err)
+
+	msg, err := decodeMessage(c.codec, raw)
+	require.NoError(c.t, err)
+
+	return msg
+}
+
+func (c *testClient) send(cmd *Command) {
+	c.t.Helper()
+
+	b, err := encodeCommand(c.codec, cmd)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.conn.WriteMessage(c.codec.messageType(), b))
+}
+
+func identifier(channel string) string {
+	b, _ := json.Marshal(map[string]string{"channel": channel})
+	return string(b)
+}
+
+func startServer(t *testing.T, config Config) *Server {
+	t.Helper()
+
+	s, err := Start(config)
+	require.NoError(t, err)
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestWelcomeAndSubscriptions(t *testing.T) {
+	s := startServer(t, Config{
+		Channels: map[string]*Channel{
+			"EchoChannel":   {Echo: true},
+			"RejectChannel": {Reject: true},
+		},
+	})
+
+	for _, subprotocol := range Subprotocols {
+		subprotocol := subprotocol
+
+		t.Run(subprotocol, func(t *testing.T) {
+			c := dial(t, s, subprotocol, nil)
+
+			welcome := c.receive()
+			assert.Equal(t, "welcome", welcome.Type)
+
+			c.send(&Command{Command: "subscribe", Identifier: identifier("EchoChannel")})
+			msg := c.receive()
+			assert.Equal(t, "confirm_subscription", msg.Type)
+			assert.Equal(t, identifier("EchoChannel"), msg.Identifier)
+
+			c.send(&Command{Command: "subscribe", Identifier: identifier("RejectChannel")})
+			msg = c.receive()
+			assert.Equal(t, "reject_subscription", msg.Type)
+
+			c.send(&Command{Command: "subscribe", Identifier: identifier("UnknownChannel")})
+			msg = c.receive()
+			assert.Equal(t, "reject_subscription", msg.Type)
+
+			c.send(&Command{Command: "message", Identifier: identifier("EchoChannel"), Data: `{"action":"echo","text":"hello"}`})
+			msg = c.receive()
+			assert.Equal(t, identifier("EchoChannel"), msg.Identifier)
+			assert.Equal(t, "hello", msg.Message.(map[string]interface{})["text"])
+		})
+	}
+}
+
+func TestDuplicateSubscribeIsIgnored(t *testing.T) {
+	s := startServer(t, Config{Channels: map[string]*Channel{"EchoChannel": {Echo: true}}})
+	c := dial(t, s, "actioncable-v1-json", nil)
+	c.receive()
+
+	c.send(&Command{Command: "subscribe", Identifier: identifier("EchoChannel")})
+	assert.Equal(t, "confirm_subscription", c.receive().Type)
+
+	c.send(&Command{Command: "subscribe", Identifier: identifier("EchoChannel")})
+	c.send(&Command{Command: "message", Identifier: identifier("EchoChannel"), Data: `{"action":"echo"}`})
+
+	// The next message must be the echo response, not the confirmation
+	msg := c.receive()
+	assert.Equal(t, "", msg.Type)
+	assert.Equal(t, "echo", msg.Message.(map[string]interface{})["action"])
+}
+
+func TestBroadcasts(t *testing.T) {
+	s := startServer(t, Config{
+		Channels: map[string]*Channel{
+			"NewsChannel": {
+				Echo: true,
+				Broadcasts: []Broadcast{
+					{Delay: 10 * time.Millisecond, Message: map[string]interface{}{"n": 1}},
+					{Delay: 20 * time.Millisecond, Message: map[string]interface{}{"n": 2}},
+				},
+			},
+		},
+	})
+
+	a := dial(t, s, "actioncable-v1-json", nil)
+	b := dial(t, s, "actioncable-v1-msgpack", nil)
+
+	for _, c := range []*testClient{a, b} {
+		c.receive()
+		c.send(&Command{Command: "subscribe", Identifier: identifier("NewsChannel")})
+		assert.Equal(t, "confirm_subscription", c.receive().Type)
+	}
+
+	for _, c := range []*testClient{a, b} {
+		assert.EqualValues(t, 1, c.receive().Message.(map[string]interface{})["n"])
+		assert.EqualValues(t, 2, c.receive().Message.(map[string]interface{})["n"])
+	}
+
+	assert.Equal(t, 2, s.Broadcast("NewsChannel", map[string]interface{}{"n": 3}))
+
+	for _, c := range []*testClient{a, b} {
+		assert.EqualValues(t, 3, c.receive().Message.(map[string]interface{})["n"])
+	}
+
+	a.send(&Command{Command: "message", Identifier: identifier("NewsChannel"), Data: `{"action":"broadcast","n":4}`})
+
+	for _, c := range []*testClient{a, b} {
+		assert.EqualValues(t, 4, c.receive().Message.(map[string]interface{})["n"])
+	}
+
+	a.send(&Command{Command: "unsubscribe", Identifier: identifier("NewsChannel")})
+
+	require.Eventually(t, func() bool {
+		return s.Broadcast("NewsChannel", map[string]interface{}{"n": 5}) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDelays(t *testing.T) {
+	s := startServer(t, Config{
+		WelcomeDelay: 50 * time.Millisecond,
+		Channels: map[string]*Channel{
+			"SlowChannel": {Echo: true, ConfirmDelay: 50 * time.Millisecond, EchoDelay: 50 * time.Millisecond},
+		},
+	})
+
+	start := time.Now()
+
+	c := dial(t, s, "actioncable-v1-json", nil)
+	c.receive()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	start = time.Now()
+	c.send(&Command{Command: "subscribe", Identifier: identifier("SlowChannel")})
+	c.receive()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	start = time.Now()
+	c.send(&Command{Command: "message", Identifier: identifier("SlowChannel"), Data: `{"action":"echo"}`})
+	c.receive()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestPings(t *testing.T) {
+	s := startServer(t, Config{PingInterval: 20 * time.Millisecond})
+
+	c := dial(t, s, "actioncable-v1-protobuf", nil)
+	c.receive()
+
+	ping := c.receive()
+	assert.Equal(t, "ping", ping.Type)
+	assert.NotNil(t, ping.Message)
+}
+
+func TestAuthenticateAndDisconnect(t *testing.T) {
+	s := startServer(t, Config{
+		Authenticate: func(r *http.Request) bool {
+			_, err := r.Cookie("user_id")
+			return err == nil
+		},
+	})
+
+	c := dial(t, s, "actioncable-v1-json", nil)
+
+	msg := c.receive()
+	assert.Equal(t, "disconnect", msg.Type)
+	assert.Equal(t, "unauthorized", msg.Reason)
+	require.NotNil(t, msg.Reconnect)
+	assert.False(t, *msg.Reconnect)
+
+	c = dial(t, s, "actioncable-v1-json", http.Header{"Cookie": []string{"user_id=42"}})
+	assert.Equal(t, "welcome", c.receive().Type)
+	assert.Equal(t, 1, s.ConnectionsCount())
+
+	s.Disconnect("server_restart", true)
+
+	msg = c.receive()
+	assert.Equal(t, "disconnect", msg.Type)
+	assert.Equal(t, "server_restart", msg.Reason)
+	assert.True(t, *msg.Reconnect)
+
+	require.Eventually(t, func() bool { return s.ConnectionsCount() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestCommands(t *testing.T) {
+	s := startServer(t, Config{Channels: map[string]*Channel{"EchoChannel": {}}})
+
+	c := dial(t, s, "actioncable-v1-json", nil)
+	c.receive()
+
+	c.send(&Command{Command: "subscribe", Identifier: identifier("EchoChannel")})
+	c.receive()
+
+	// Invalid frames are ignored
+	require.NoError(t, c.conn.WriteMessage(websocket.TextMessage, []byte("not a json")))
+
+	c.send(&Command{Command: "unsubscribe", Identifier: identifier("EchoChannel")})
+
+	require.Eventually(t, func() bool { return len(s.Commands()) == 2 }, time.Second, 10*time.Millisecond)
+
+	commands := s.Commands()
+	assert.Equal(t, "subscribe", commands[0].Command)
+	assert.Equal(t, "unsubscribe", commands[1].Command)
+}
+
+func TestCommandsLimit(t *testing.T) {
+	s := &Server{config: Config{MaxCommands: 2}}
+
+	for _, name := range []string{"subscribe", "message", "unsubscribe"} {
+		s.recordCommand(&Command{Command: name})
+	}
+
+	// Only the most recent commands are kept
+	commands := s.Commands()
+	require.Len(t, commands, 2)
+	assert.Equal(t, "message", commands[0].Command)
+	assert.Equal(t, "unsubscribe", commands[1].Command)
+
+	// Commands are returned as a copy
+	commands[0] = nil
+	assert.NotNil(t, s.Commands()[0])
+}
+
+// decodeMessage decodes the server message the way clients do
+func decodeMessage(c codec, raw []byte) (*Message, error) {
+	msg := &Message{}
+
+	switch c.(type) {
+	case msgpackCodec:
+		return msg, msgpack.Unmarshal(raw, msg)
+	case protobufCodec:
+		buf := &pb.Message{}
+		if err := proto.Unmarshal(raw, buf); err != nil {
+			return nil, err
+		}
+
+		msg.Type = buf.Type.String()
+		msg.Identifier = buf.Identifier
+		msg.Reason = buf.Reason
+
+		if buf.Type == pb.Type_disconnect {
+			msg.Reconnect = &buf.Reconnect
+		}
+
+		if buf.Message != nil {
+			if err := msgpack.Unmarshal(buf.Message, &msg.Message); err != nil {
+				return nil, err
+			}
+		}
+
+		// Protobuf doesn't distinguish missing type from no_type
+		if msg.Type == "no_type" {
+			msg.Type = ""
+		}
+
+		return msg, nil
+	default:
+		return msg, json.Unmarshal(raw, msg)
+	}
+}
+
+// encodeCommand encodes the command the way clients do
+func encodeCommand(c codec, cmd *Command) ([]byte, error) {
+	switch c.(type) {
+	case msgpackCodec:
+		return msgpack.Marshal(cmd)
+	case protobufCodec:
+		return proto.Marshal(&pb.Message{
+			Command:    pb.Command(pb.Command_value[cmd.Command]),
+			Identifier: cmd.Identifier,
+			Data:       cmd.Data,
+		})
+	default:
+		return json.Marshal(cmd)
+	}
+}
@@ -1,6 +1,9 @@
 package cable
 
-import "go.k6.io/k6/js/modules"
+import (
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+)
 
 func init() {
 	modules.Register("k6/x/cable", New())
@@ -8,9 +11,17 @@ func init() {
 
 type (
 	Cable struct {
-		vu modules.VU
+		vu      modules.VU
+		metrics *cableMetrics
+		root    *RootModule
+		// scriptDir is the directory of the main script (relative file paths are resolved against it)
+		scriptDir string
+	}
+	// RootModule contains the state shared by all the VUs
+	RootModule struct {
+		trackers *trackerRegistry
+		barriers *barrierRegistry
 	}
-	RootModule  struct{}
 	CableModule struct {
 		*Cable
 	}
@@ -22,11 +33,21 @@ var (
 )
 
 func New() *RootModule {
-	return &RootModule{}
+	return &RootModule{trackers: newTrackerRegistry(), barriers: newBarrierRegistry()}
 }
 
-func (*RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
-	return &CableModule{Cable: &Cable{vu: vu}}
+func (r *RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
+	m, err := registerMetrics(vu)
+	if err != nil {
+		common.Throw(vu.Runtime(), err)
+	}
+
+	var scriptDir string
+	if env := vu.InitEnv(); env != nil && env.CWD != nil && env.CWD.Scheme == "file" {
+		scriptDir = env.CWD.Path
+	}
+
+	return &CableModule{Cable: &Cable{vu: vu, metrics: m, root: r, scriptDir: scriptDir}}
 }
 
 func (c *CableModule) Exports() modules.Exports {
@@ -0,0 +1,275 @@
+package cable
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+type pusherOptions struct {
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+}
+
+type pusherEvent struct {
+	Event   string          `json:"event"`
+	Channel string          `json:"channel,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// pusherTransport translates Action Cable commands and messages to and from the Pusher protocol.
+// Channel identifiers are mapped to Pusher channel names (taken from the `channel` param).
+type pusherTransport struct {
+	conn *websocket.Conn
+	opts pusherOptions
+
+	tap *rawTap
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	socketID    string
+	identifiers map[string]string
+}
+
+var (
+	_ transport    = (*pusherTransport)(nil)
+	_ rawTransport = (*pusherTransport)(nil)
+)
+
+func newPusherTransport(conn *websocket.Conn, opts *pusherOptions) *pusherTransport {
+	t := &pusherTransport{conn: conn, identifiers: make(map[string]string)}
+
+	if opts != nil {
+		t.opts = *opts
+	}
+
+	return t
+}
+
+func (t *pusherTransport) Receive(msg *cableMsg) (int, error) {
+	mtype, raw, err := t.conn.ReadMessage()
+	if err != nil {
+		return 0, err
+	}
+
+	t.tap.push(raw, mtype == websocket.BinaryMessage)
+
+	var event pusherEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return len(raw), &decodeError{err}
+	}
+
+	data := decodePusherData(event.Data)
+
+	switch event.Event {
+	case "pusher:connection_established":
+		msg.Type = "welcome"
+
+		if info, ok := data.(map[string]interface{}); ok {
+			socketID, _ := info["socket_id"].(string)
+
+			t.mu.Lock()
+			t.socketID = socketID
+			t.mu.Unlock()
+
+			msg.SID = socketID
+		}
+	case "pusher:ping":
+		msg.Type = "ping"
+
+		if err := t.write(&pusherEvent{Event: "pusher:pong", Data: json.RawMessage("{}")}); err != nil {
+			return len(raw), err
+		}
+	case "pusher_internal:subscription_succeeded":
+		msg.Type = "confirm_subscription"
+		msg.Identifier = t.identifier(event.Channel)
+	case "pusher:subscription_error":
+		msg.Type = "reject_subscription"
+		msg.Identifier = t.identifier(event.Channel)
+	case "pusher:error":
+		msg.Type = "error"
+		msg.Message = data
+	default:
+		msg.Identifier = t.identifier(event.Channel)
+		msg.Message = map[string]interface{}{
+			"event": event.Event,
+			"data":  data,
+		}
+	}
+
+	return len(raw), nil
+}
+
+func (t *pusherTransport) Send(msg *cableMsg) (int, error) {
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(msg.Identifier), &params); err != nil {
+		return 0, err
+	}
+
+	channel, _ := params["channel"].(string)
+
+	var event *pusherEvent
+
+	switch msg.Command {
+	case "subscribe":
+		t.mu.Lock()
+		t.identifiers[channel] = msg.Identifier
+		t.mu.Unlock()
+
+		data, err := t.subscribeData(channel, params)
+		if err != nil {
+			return 0, err
+		}
+
+		event = &pusherEvent{Event: "pusher:subscribe", Data: data}
+	case "unsubscribe":
+		data, _ := json.Marshal(map[string]string{"channel": channel})
+		event = &pusherEvent{Event: "pusher:unsubscribe", Data: data}
+	case "message":
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(msg.Data), &payload); err != nil {
+			return 0, err
+		}
+
+		action, _ := payload["action"].(string)
+		delete(payload, "action")
+
+		// Pusher only allows client events to be prefixed with `client-`
+		if !strings.HasPrefix(action, "client-") {
+			action = "client-" + action
+		}
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, err
+		}
+
+		event = &pusherEvent{Event: action, Channel: channel, Data: data}
+	default:
+		return 0, fmt.Errorf("unsupported command for pusher protocol: %s", msg.Command)
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	return len(b), t.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+func (t *pusherTransport) SendRaw(data []byte, binary bool) (int, error) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	return len(data), t.conn.WriteMessage(wsMessageType(binary), data)
+}
+
+func (t *pusherTransport) setTap(tap *rawTap) {
+	t.tap = tap
+}
+
+func (t *pusherTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *pusherTransport) write(event *pusherEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	return t.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+func (t *pusherTransport) identifier(channel string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if identifier, ok := t.identifiers[channel]; ok {
+		return identifier
+	}
+
+	return channel
+}
+
+// subscribeData builds the pusher:subscribe payload.
+// For private and presence channels, the auth signature is either taken from the `auth` param
+// or calculated using the app key and secret.
+// For presence channels, the channel data is either taken from the `channel_data` param
+// or built from the `user_id` and `user_info` params.
+func (t *pusherTransport) subscribeData(channel string, params map[string]interface{}) (json.RawMessage, error) {
+	data := map[string]string{"channel": channel}
+
+	if strings.HasPrefix(channel, "presence-") {
+		if channelData, ok := params["channel_data"].(string); ok {
+			data["channel_data"] = channelData
+		} else if userID, ok := params["user_id"]; ok {
+			channelData, err := json.Marshal(map[string]interface{}{
+				"user_id":   fmt.Sprintf("%v", userID),
+				"user_info": params["user_info"],
+			})
+			if err != nil {
+				return nil, err
+			}
+			data["channel_data"] = string(channelData)
+		}
+	}
+
+	if auth, ok := params["auth"].(string); ok {
+		data["auth"] = auth
+	} else if t.opts.Secret != "" && (strings.HasPrefix(channel, "private-") || strings.HasPrefix(channel, "presence-")) {
+		data["auth"] = t.sign(channel, data["channel_data"])
+	}
+
+	return json.Marshal(data)
+}
+
+// sign generates the auth signature for private and presence channels
+func (t *pusherTransport) sign(channel string, channelData string) string {
+	t.mu.Lock()
+	toSign := t.socketID + ":" + channel
+	t.mu.Unlock()
+
+	if channelData != "" {
+		toSign += ":" + channelData
+	}
+
+	mac := hmac.New(sha256.New, []byte(t.opts.Secret))
+	mac.Write([]byte(toSign))
+
+	return t.opts.Key + ":" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// decodePusherData decodes event data, which is usually a JSON-encoded string
+func decodePusherData(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return string(raw)
+	}
+
+	if str, ok := data.(string); ok {
+		var nested interface{}
+		if err := json.Unmarshal([]byte(str), &nested); err == nil {
+			return nested
+		}
+	}
+
+	return data
+}
@@ -0,0 +1,227 @@
+package cable
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	pusherTestKey      = "app-key"
+	pusherTestSecret   = "app-secret"
+	pusherTestSocketID = "123.456"
+)
+
+// pusherServer is a minimal Pusher protocol stand-in: it verifies private and presence channels signatures
+// and echoes client events back to the sender.
+type pusherServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	events []pusherEvent
+}
+
+func startPusherServer(t *testing.T) *pusherServer {
+	t.Helper()
+
+	s := &pusherServer{}
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		s.serve(conn)
+	}))
+
+	t.Cleanup(func() {
+		s.CloseClientConnections()
+		s.Close()
+	})
+
+	return s
+}
+
+func (s *pusherServer) serve(conn *websocket.Conn) {
+	send := func(event string, channel string, data interface{}) error {
+		encoded, _ := json.Marshal(data)
+		payload, _ := json.Marshal(map[string]interface{}{"event": event, "channel": channel, "data": string(encoded)})
+		return conn.WriteMessage(websocket.TextMessage, payload)
+	}
+
+	if send("pusher:connection_established", "", map[string]interface{}{"socket_id": pusherTestSocketID, "activity_timeout": 120}) != nil {
+		return
+	}
+
+	if send("pusher:ping", "", map[string]interface{}{}) != nil {
+		return
+	}
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var event pusherEvent
+		if json.Unmarshal(raw, &event) != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		s.events = append(s.events, event)
+		s.mu.Unlock()
+
+		switch {
+		case event.Event == "pusher:subscribe":
+			var data map[string]string
+			_ = json.Unmarshal(event.Data, &data)
+
+			channel := data["channel"]
+
+			if strings.HasPrefix(channel, "private-") || strings.HasPrefix(channel, "presence-") {
+				if data["auth"] != pusherSignature(channel, data["channel_data"]) {
+					_ = send("pusher:subscription_error", channel, map[string]interface{}{"type": "AuthError", "status": 401})
+					continue
+				}
+			}
+
+			_ = send("pusher_internal:subscription_succeeded", channel, map[string]interface{}{})
+		case strings.HasPrefix(event.Event, "client-"):
+			var data interface{}
+			_ = json.Unmarshal(event.Data, &data)
+
+			_ = send(event.Event, event.Channel, data)
+		}
+	}
+}
+
+func (s *pusherServer) receivedEvents() []pusherEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]pusherEvent(nil), s.events...)
+}
+
+func pusherSignature(channel string, channelData string) string {
+	toSign := pusherTestSocketID + ":" + channel
+	if channelData != "" {
+		toSign += ":" + channelData
+	}
+
+	mac := hmac.New(sha256.New, []byte(pusherTestSecret))
+	mac.Write([]byte(toSign))
+
+	return pusherTestKey + ":" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newPusherTestState(t *testing.T) (*testState, *pusherServer) {
+	t.Helper()
+
+	ts := newTestState(t)
+	server := startPusherServer(t)
+
+	require.NoError(t, ts.VU.Runtime().Set("PUSHER_URL", "ws"+strings.TrimPrefix(server.URL, "http")+"/app/"+pusherTestKey))
+
+	return ts, server
+}
+
+func TestPusherConnectionEstablished(t *testing.T) {
+	ts, server := newPusherTestState(t)
+
+	val := ts.run(t, `
+		const client = cable.connect(PUSHER_URL, { protocol: "pusher" });
+		client.sessionID()
+	`)
+
+	assert.Equal(t, pusherTestSocketID, val.String())
+
+	// Pings are answered with pongs
+	require.Eventually(t, func() bool {
+		for _, event := range server.receivedEvents() {
+			if event.Event == "pusher:pong" {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPusherPrivateChannelAuth(t *testing.T) {
+	ts, server := newPusherTestState(t)
+
+	ts.run(t, `
+		const client = cable.connect(PUSHER_URL, { protocol: "pusher", pusher: { key: "app-key", secret: "app-secret" } });
+		client.subscribe("private-chat");
+		client.subscribe("presence-room", { user_id: 42, user_info: { name: "Jack" } });
+	`)
+
+	var subscribes []map[string]string
+
+	for _, event := range server.receivedEvents() {
+		if event.Event == "pusher:subscribe" {
+			var data map[string]string
+			require.NoError(t, json.Unmarshal(event.Data, &data))
+			subscribes = append(subscribes, data)
+		}
+	}
+
+	require.Len(t, subscribes, 2)
+
+	assert.Equal(t, pusherSignature("private-chat", ""), subscribes[0]["auth"])
+	assert.JSONEq(t, `{"user_id":"42","user_info":{"name":"Jack"}}`, subscribes[1]["channel_data"])
+	assert.Equal(t, pusherSignature("presence-room", subscribes[1]["channel_data"]), subscribes[1]["auth"])
+
+	_, err := ts.VU.Runtime().RunString(`
+		const invalid = cable.connect(PUSHER_URL, { protocol: "pusher", pusher: { key: "app-key", secret: "wrong" } });
+		invalid.subscribe("private-chat");
+	`)
+	assert.ErrorContains(t, err, "rejected")
+}
+
+func TestPusherClientEvents(t *testing.T) {
+	ts, server := newPusherTestState(t)
+
+	val := ts.run(t, `
+		const client = cable.connect(PUSHER_URL, { protocol: "pusher" });
+		const channel = client.subscribe("chat");
+
+		channel.perform("typing", { user: "jack" });
+		channel.perform("client-speak", { text: "hello" });
+
+		channel.receive({ event: "client-speak" }).data.text
+	`)
+
+	assert.Equal(t, "hello", val.String())
+
+	var clientEvents []pusherEvent
+
+	for _, event := range server.receivedEvents() {
+		if strings.HasPrefix(event.Event, "client-") {
+			clientEvents = append(clientEvents, event)
+		}
+	}
+
+	require.Len(t, clientEvents, 2)
+
+	// Actions are prefixed with client-
+	assert.Equal(t, "client-typing", clientEvents[0].Event)
+	assert.Equal(t, "chat", clientEvents[0].Channel)
+	assert.JSONEq(t, `{"user":"jack"}`, string(clientEvents[0].Data))
+
+	assert.Equal(t, "client-speak", clientEvents[1].Event)
+}
@@ -0,0 +1,134 @@
+package cable
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/metrics"
+)
+
+const rawFramesBufferSize = 1024
+
+type rawFrame struct {
+	data   []byte
+	binary bool
+}
+
+// rawTap captures incoming frames as is (before decoding).
+// Capturing is disabled until the raw frames API is used.
+type rawTap struct {
+	enabled int32
+	ch      chan *rawFrame
+}
+
+func newRawTap() *rawTap {
+	return &rawTap{ch: make(chan *rawFrame, rawFramesBufferSize)}
+}
+
+func (t *rawTap) enable() {
+	atomic.StoreInt32(&t.enabled, 1)
+}
+
+func (t *rawTap) push(data []byte, binary bool) {
+	if t == nil || atomic.LoadInt32(&t.enabled) == 0 {
+		return
+	}
+
+	// Never block the receive loop: drop frames if nobody reads them
+	select {
+	case t.ch <- &rawFrame{data: data, binary: binary}:
+	default:
+	}
+}
+
+type sendRawOptions struct {
+	Binary *bool `json:"binary"`
+}
+
+// SendRaw sends the data (string or ArrayBuffer) as is, bypassing the codec.
+// Strings are sent as text frames and ArrayBuffers as binary frames unless the `binary` option is provided.
+func (c *Client) SendRaw(data sobek.Value, optsIn sobek.Value) error {
+	var opts sendRawOptions
+	if err := decodeOptions(c.vu.Runtime(), optsIn, &opts); err != nil {
+		return err
+	}
+
+	exported := data.Export()
+
+	payload, err := common.ToBytes(exported)
+	if err != nil {
+		return err
+	}
+
+	_, isString := exported.(string)
+	binary := !isString
+	if opts.Binary != nil {
+		binary = *opts.Binary
+	}
+
+	return c.sendRaw(payload, binary)
+}
+
+func (c *Client) sendRaw(payload []byte, binary bool) error {
+	state := c.vu.State()
+	if state == nil {
+		return errCableInInitContext
+	}
+
+	rt, ok := c.transport.(rawTransport)
+	if !ok {
+		return fmt.Errorf("raw frames are not supported by the transport")
+	}
+
+	c.raw.enable()
+
+	size, err := rt.SendRaw(payload, binary)
+	now := time.Now()
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: state.BuiltinMetrics.WSMessagesSent,
+			Tags:   c.sampleTags,
+		},
+		Time:  now,
+		Value: 1,
+	})
+
+	if err == nil {
+		c.trackMessageSize(c.metrics.MessageSizeSent, "", "raw", size, now)
+	}
+
+	return err
+}
+
+// ReceiveRaw returns the next incoming frame as is: text frames are returned as strings and binary frames as ArrayBuffers.
+// Frames are only captured after the raw frames API has been used for the first time.
+// Returns null if no frames received in time.
+func (c *Client) ReceiveRaw(timeoutMs int) sobek.Value {
+	if _, ok := c.transport.(rawTransport); !ok {
+		panic("raw frames are not supported by the transport")
+	}
+
+	c.raw.enable()
+
+	timeout := c.recTimeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case frame := <-c.raw.ch:
+		if frame.binary {
+			return c.vu.Runtime().ToValue(c.vu.Runtime().NewArrayBuffer(frame.data))
+		}
+		return c.vu.Runtime().ToValue(string(frame.data))
+	case <-timer.C:
+		return sobek.Null()
+	}
+}
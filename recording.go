@@ -0,0 +1,318 @@
+package cable
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	recordDirectionConnect = "connect"
+	recordDirectionIn      = "in"
+	recordDirectionOut     = "out"
+	recordDirectionClose   = "close"
+
+	recordRedacted = "[REDACTED]"
+)
+
+// defaultRedactedHeaders are always redacted in transcripts
+var defaultRedactedHeaders = []string{"Cookie", "Authorization"}
+
+// transcriptEntry is a single line of the JSONL transcript
+type transcriptEntry struct {
+	Timestamp  time.Time         `json:"timestamp"`
+	Session    string            `json:"session,omitempty"`
+	Direction  string            `json:"direction"`
+	Identifier string            `json:"identifier,omitempty"`
+	Size       int               `json:"size,omitempty"`
+	Message    interface{}       `json:"message,omitempty"`
+	Raw        bool              `json:"raw,omitempty"`
+	Binary     bool              `json:"binary,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	URL        string            `json:"url,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	VU         uint64            `json:"vu,omitempty"`
+}
+
+// recordingFile is a transcript file shared by all the clients recording to the same path
+type recordingFile struct {
+	mu   sync.Mutex
+	f    *os.File
+	refs int
+}
+
+var (
+	recordingFilesMu sync.Mutex
+	recordingFiles   = make(map[string]*recordingFile)
+
+	// recordingSeq is used to generate unique transcript names for prefixes
+	recordingSeq uint64
+)
+
+func openRecordingFile(path string) (*recordingFile, error) {
+	recordingFilesMu.Lock()
+	defer recordingFilesMu.Unlock()
+
+	if rf, ok := recordingFiles[path]; ok {
+		rf.refs++
+		return rf, nil
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &recordingFile{f: f, refs: 1}
+	recordingFiles[path] = rf
+
+	return rf, nil
+}
+
+func closeRecordingFile(path string, rf *recordingFile) {
+	recordingFilesMu.Lock()
+	defer recordingFilesMu.Unlock()
+
+	rf.refs--
+	if rf.refs > 0 {
+		return
+	}
+
+	delete(recordingFiles, path)
+	_ = rf.f.Close()
+}
+
+// recorder writes client frames to the JSONL transcript
+type recorder struct {
+	path string
+	file *recordingFile
+
+	// session distinguishes clients recording to the same file
+	session string
+
+	closeOnce sync.Once
+	closed    int32
+}
+
+// newRecorder returns a recorder for the client (or nil if the VU is not sampled).
+// When the record option ends with .jsonl, it's used as a path (shared by all the clients);
+// otherwise, it's used as a prefix for a per-client transcript file.
+func newRecorder(cOpts *connectOptions, vuID uint64) (*recorder, error) {
+	if cOpts.Record == "" || !recordingSampled(cOpts.RecordSampleRate, vuID) {
+		return nil, nil
+	}
+
+	path := cOpts.Record
+	if !strings.HasSuffix(path, ".jsonl") {
+		path = fmt.Sprintf("%s-vu%d-%d.jsonl", path, vuID, atomic.AddUint64(&recordingSeq, 1))
+	}
+
+	file, err := openRecordingFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript %s: %w", path, err)
+	}
+
+	session, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &recorder{path: path, file: file, session: session}, nil
+}
+
+// recordingSampled returns true if the VU must be recorded for the specified sample rate.
+// The decision is stable for the VU (so all the VU clients are either recorded or not).
+func recordingSampled(rate *float64, vuID uint64) bool {
+	if rate == nil || *rate >= 1 {
+		return true
+	}
+
+	if *rate <= 0 {
+		return false
+	}
+
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%d", vuID)
+
+	return float64(h.Sum32()%10000) < *rate*10000
+}
+
+func (r *recorder) write(entry *transcriptEntry) {
+	if atomic.LoadInt32(&r.closed) == 1 {
+		return
+	}
+
+	entry.Session = r.session
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line, _ = json.Marshal(&transcriptEntry{Timestamp: entry.Timestamp, Session: r.session, Direction: entry.Direction, Error: err.Error()})
+	}
+
+	line = append(line, '\n')
+
+	r.file.mu.Lock()
+	defer r.file.mu.Unlock()
+
+	_, _ = r.file.f.Write(line)
+}
+
+func (r *recorder) connect(cableUrl string, header http.Header, redact []string, vuID uint64) {
+	r.write(&transcriptEntry{
+		Timestamp: time.Now(),
+		Direction: recordDirectionConnect,
+		URL:       cableUrl,
+		Headers:   redactHeaders(header, redact),
+		VU:        vuID,
+	})
+}
+
+func (r *recorder) message(direction string, msg *cableMsg, size int, err error) {
+	entry := &transcriptEntry{
+		Timestamp:  time.Now(),
+		Direction:  direction,
+		Identifier: msg.Identifier,
+		Size:       size,
+		Message:    msg,
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	r.write(entry)
+}
+
+func (r *recorder) raw(data []byte, binary bool) {
+	entry := &transcriptEntry{
+		Timestamp: time.Now(),
+		Direction: recordDirectionOut,
+		Size:      len(data),
+		Raw:       true,
+		Binary:    binary,
+	}
+
+	if binary {
+		entry.Message = base64.StdEncoding.EncodeToString(data)
+	} else {
+		entry.Message = string(data)
+	}
+
+	r.write(entry)
+}
+
+func (r *recorder) close(err error) {
+	r.closeOnce.Do(func() {
+		entry := &transcriptEntry{Timestamp: time.Now(), Direction: recordDirectionClose}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+
+		r.write(entry)
+
+		atomic.StoreInt32(&r.closed, 1)
+		closeRecordingFile(r.path, r.file)
+	})
+}
+
+func redactHeaders(header http.Header, redact []string) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	redacted := make(map[string]bool)
+	for _, name := range append(defaultRedactedHeaders, redact...) {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+
+	result := make(map[string]string, len(header))
+
+	for name := range header {
+		if redacted[http.CanonicalHeaderKey(name)] {
+			result[name] = recordRedacted
+		} else {
+			result[name] = header.Get(name)
+		}
+	}
+
+	return result
+}
+
+// recordingTransport writes all the sent and received messages to the transcript
+type recordingTransport struct {
+	transport
+	rec *recorder
+}
+
+func (t *recordingTransport) Receive(msg *cableMsg) (int, error) {
+	size, err := t.transport.Receive(msg)
+
+	if err == nil {
+		t.rec.message(recordDirectionIn, msg, size, nil)
+		return size, nil
+	}
+
+	if _, ok := err.(*decodeError); ok {
+		t.rec.message(recordDirectionIn, msg, size, err)
+		return size, err
+	}
+
+	t.rec.close(err)
+
+	return size, err
+}
+
+func (t *recordingTransport) Send(msg *cableMsg) (int, error) {
+	size, err := t.transport.Send(msg)
+	t.rec.message(recordDirectionOut, msg, size, err)
+	return size, err
+}
+
+func (t *recordingTransport) Close() error {
+	err := t.transport.Close()
+	t.rec.close(nil)
+	return err
+}
+
+// recordingRawTransport is a recordingTransport for transports supporting raw frames
+type recordingRawTransport struct {
+	*recordingTransport
+	raw rawTransport
+}
+
+func (t *recordingRawTransport) SendRaw(data []byte, binary bool) (int, error) {
+	size, err := t.raw.SendRaw(data, binary)
+	if err == nil {
+		t.rec.raw(data, binary)
+	}
+	return size, err
+}
+
+func (t *recordingRawTransport) setTap(tap *rawTap) {
+	t.raw.setTap(tap)
+}
+
+// withRecorder wraps the transport to record messages (keeping the raw frames support, if any)
+func withRecorder(t transport, rec *recorder) transport {
+	rt := &recordingTransport{transport: t, rec: rec}
+
+	if raw, ok := t.(rawTransport); ok {
+		return &recordingRawTransport{recordingTransport: rt, raw: raw}
+	}
+
+	return rt
+}
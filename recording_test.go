@@ -0,0 +1,125 @@
+package cable
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTranscript(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+
+	var entries []map[string]interface{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestRecordTranscript(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	require.NoError(t, ts.VU.Runtime().Set("TRANSCRIPT", path))
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL, {
+			record: TRANSCRIPT,
+			cookies: "session=secret",
+			headers: { "X-Api-Key": "secret", "X-Trace": "visible" },
+			recordRedact: ["x-api-key"],
+		});
+
+		const channel = client.subscribe("EchoChannel");
+		channel.perform("echo", { text: "hello" });
+		channel.receive();
+
+		client.disconnect();
+	`)
+
+	// The transcript is closed asynchronously by the client
+	var entries []map[string]interface{}
+	require.Eventually(t, func() bool {
+		entries = readTranscript(t, path)
+		return len(entries) > 0 && entries[len(entries)-1]["direction"] == recordDirectionClose
+	}, time.Second, 10*time.Millisecond)
+
+	connect := entries[0]
+	assert.Equal(t, recordDirectionConnect, connect["direction"])
+	assert.Equal(t, ts.VU.Runtime().Get("CABLE_URL").String(), connect["url"])
+
+	headers := connect["headers"].(map[string]interface{})
+	assert.Equal(t, recordRedacted, headers["Cookie"])
+	assert.Equal(t, recordRedacted, headers["X-Api-Key"])
+	assert.Equal(t, "visible", headers["X-Trace"])
+
+	var directions []string
+	for _, entry := range entries[1:] {
+		directions = append(directions, entry["direction"].(string))
+		assert.NotEmpty(t, entry["timestamp"])
+	}
+
+	// welcome, subscribe, confirm, perform, echo, close
+	assert.Equal(t, []string{"in", "out", "in", "out", "in", "close"}, directions)
+
+	subscribe := entries[2]
+	assert.Equal(t, `{"channel":"EchoChannel"}`, subscribe["identifier"])
+	assert.Greater(t, subscribe["size"], 0.0)
+	assert.Equal(t, "subscribe", subscribe["message"].(map[string]interface{})["command"])
+
+	echo := entries[5]
+	assert.Equal(t, "hello", echo["message"].(map[string]interface{})["message"].(map[string]interface{})["text"])
+}
+
+func TestRecordPrefixAndSampling(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	dir := t.TempDir()
+	require.NoError(t, ts.VU.Runtime().Set("PREFIX", filepath.Join(dir, "run")))
+
+	ts.run(t, `
+		cable.connect(CABLE_URL, { record: PREFIX }).disconnect();
+		cable.connect(CABLE_URL, { record: PREFIX }).disconnect();
+		cable.connect(CABLE_URL, { record: PREFIX + "-skipped", recordSampleRate: 0 }).disconnect();
+	`)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	for _, file := range files {
+		assert.Regexp(t, `run-vu\d+-\d+\.jsonl$`, file)
+	}
+}
+
+func TestRecordingSampled(t *testing.T) {
+	rate := 0.1
+	sampled := 0
+
+	for vu := uint64(1); vu <= 10000; vu++ {
+		if recordingSampled(&rate, vu) {
+			sampled++
+		}
+	}
+
+	assert.InDelta(t, 1000, sampled, 150)
+	assert.Equal(t, recordingSampled(&rate, 42), recordingSampled(&rate, 42))
+	assert.True(t, recordingSampled(nil, 1))
+}
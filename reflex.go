@@ -0,0 +1,241 @@
+package cable
+
+import (
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+)
+
+const defaultPermanentAttributeName = "data-reflex-permanent"
+
+type reflexOptions struct {
+	Attrs                  map[string]interface{} `json:"attrs"`
+	Dataset                map[string]interface{} `json:"dataset"`
+	Selectors              []string               `json:"selectors"`
+	PermanentAttributeName string                 `json:"permanentAttributeName"`
+	URL                    string                 `json:"url"`
+	FormData               string                 `json:"formData"`
+	ReflexID               string                 `json:"reflexId"`
+	TabID                  string                 `json:"tabId"`
+	Version                string                 `json:"version"`
+	TimeoutMs              int                    `json:"timeoutMs"`
+}
+
+// ReflexResult contains the CableReady operations received in response to the reflex
+type ReflexResult struct {
+	ReflexID   string        `js:"reflexId"`
+	Operations []interface{} `js:"operations"`
+	Duration   int64         `js:"duration"`
+	Error      string        `js:"error"`
+}
+
+// Reflex invokes the StimulusReflex reflex and waits for the corresponding CableReady operations
+// until the reflex is finalized: either a page or selector morph or a server message (e.g., "nothing", "error", "halted") is received.
+// Other messages received in the meantime are kept in the inbox.
+// Returns null if the reflex hasn't been finalized in time.
+func (ch *Channel) Reflex(target string, argsIn sobek.Value, optsIn sobek.Value) (*ReflexResult, error) {
+	var opts reflexOptions
+	if err := decodeOptions(ch.client.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{}
+	if argsIn != nil && !sobek.IsUndefined(argsIn) && !sobek.IsNull(argsIn) {
+		if exported, ok := argsIn.Export().([]interface{}); ok {
+			args = exported
+		}
+	}
+
+	reflexID := opts.ReflexID
+	if reflexID == "" {
+		id, err := randomUUID()
+		if err != nil {
+			return nil, err
+		}
+		reflexID = id
+	}
+
+	payload := map[string]interface{}{
+		"target":                 target,
+		"args":                   args,
+		"attrs":                  opts.Attrs,
+		"dataset":                opts.Dataset,
+		"selectors":              opts.Selectors,
+		"reflexId":               reflexID,
+		"permanentAttributeName": opts.PermanentAttributeName,
+		"url":                    opts.URL,
+		"formData":               opts.FormData,
+		"resolveLate":            false,
+	}
+
+	if opts.Attrs == nil {
+		payload["attrs"] = map[string]interface{}{}
+	}
+
+	if opts.Dataset == nil {
+		payload["dataset"] = map[string]interface{}{"dataset": map[string]interface{}{}, "datasetAll": map[string]interface{}{}}
+	}
+
+	if opts.Selectors == nil {
+		payload["selectors"] = []string{}
+	}
+
+	if opts.PermanentAttributeName == "" {
+		payload["permanentAttributeName"] = defaultPermanentAttributeName
+	}
+
+	if opts.TabID != "" {
+		payload["tabId"] = opts.TabID
+	}
+
+	if opts.Version != "" {
+		payload["version"] = opts.Version
+	}
+
+	timeout := ch.client.recTimeout
+	if opts.TimeoutMs > 0 {
+		timeout = time.Duration(opts.TimeoutMs) * time.Millisecond
+	}
+
+	start := time.Now()
+
+	if err := ch.perform("receive", payload); err != nil {
+		return nil, err
+	}
+
+	matcher := &reflexMatcher{reflexID: reflexID}
+	result := &ReflexResult{ReflexID: reflexID}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	// Messages unrelated to the reflex are kept in the inbox
+	var skipped []*cableMsg
+	defer func() { ch.unshift(skipped) }()
+
+	for {
+		msg := ch.next(timer.C)
+		if msg == nil {
+			ch.logger.Warnf("reflex %s timeout exceeded; consider increasing timeoutMs option", target)
+			return nil, nil
+		}
+
+		if !matcher.Match(msg.Message) {
+			skipped = append(skipped, msg)
+			continue
+		}
+
+		completed := false
+
+		ops, _ := cableReadyOperations(msg.Message)
+		for _, op := range ops {
+			if reflexOperationID(op) != reflexID {
+				continue
+			}
+
+			result.Operations = append(result.Operations, op)
+
+			if subject := reflexServerMessageSubject(op); subject != "" {
+				completed = true
+
+				if subject == "error" || subject == "halted" || subject == "forbidden" {
+					result.Error = subject
+				}
+			} else if isReflexMorph(op) {
+				completed = true
+			}
+		}
+
+		// Operations broadcasted by the reflex itself (e.g., via `cable_ready.broadcast`)
+		// could arrive before the morph
+		if !completed {
+			continue
+		}
+
+		duration := msg.receivedAt.Sub(start)
+		result.Duration = duration.Milliseconds()
+
+		ch.trackReflex(target, duration, msg.receivedAt)
+
+		return result, nil
+	}
+}
+
+func (ch *Channel) trackReflex(target string, d time.Duration, when time.Time) {
+	c := ch.client
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.ReflexDuration,
+			Tags:   c.sampleTags.With("reflex", target),
+		},
+		Time:  when,
+		Value: metrics.D(d),
+	})
+}
+
+// reflexMatcher matches CableReady messages containing operations for the specified reflex
+type reflexMatcher struct {
+	reflexID string
+}
+
+func (m *reflexMatcher) Match(msg interface{}) bool {
+	ops, ok := cableReadyOperations(msg)
+	if !ok {
+		return false
+	}
+
+	for _, op := range ops {
+		if reflexOperationID(op) == m.reflexID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reflexOperationID returns the reflex ID the operation belongs to.
+// Depending on the StimulusReflex version, the ID could be stored in the operation itself,
+// in the `stimulusReflex` object, or in the event details (for `dispatchEvent` operations).
+func reflexOperationID(op map[string]interface{}) string {
+	if id, ok := op["reflexId"].(string); ok {
+		return id
+	}
+
+	for _, path := range []string{"stimulusReflex.reflexId", "detail.reflexId", "detail.stimulusReflex.reflexId"} {
+		if val, ok := lookupPath(op, path); ok {
+			if id, ok := val.(string); ok {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
+
+// isReflexMorph returns true if the operation is a page or selector morph (which finalizes the reflex)
+func isReflexMorph(op map[string]interface{}) bool {
+	for _, path := range []string{"stimulusReflex.morph", "detail.stimulusReflex.morph"} {
+		if val, ok := lookupPath(op, path); ok {
+			if morph, ok := val.(string); ok && (morph == "page" || morph == "selector") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// reflexServerMessageSubject returns the subject of the StimulusReflex server message (e.g., "error", "halted", "nothing")
+func reflexServerMessageSubject(op map[string]interface{}) string {
+	for _, path := range []string{"stimulusReflex.serverMessage.subject", "detail.stimulusReflex.serverMessage.subject"} {
+		if val, ok := lookupPath(op, path); ok {
+			if subject, ok := val.(string); ok {
+				return subject
+			}
+		}
+	}
+
+	return ""
+}
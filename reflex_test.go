@@ -0,0 +1,139 @@
+package cable
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+// respondToReflex waits for the reflex with the ID to be performed and broadcasts the messages built for it
+func respondToReflex(t *testing.T, server *mockserver.Server, reflexID string, respond func(reflexID string) []interface{}) {
+	t.Helper()
+
+	go func() {
+		deadline := time.Now().Add(time.Second)
+
+		for time.Now().Before(deadline) {
+			for _, cmd := range server.Commands() {
+				if cmd.Command != "message" {
+					continue
+				}
+
+				var data map[string]interface{}
+				if json.Unmarshal([]byte(cmd.Data), &data) != nil || data["reflexId"] != reflexID {
+					continue
+				}
+
+				for _, msg := range respond(reflexID) {
+					server.BroadcastTo(cmd.Identifier, msg)
+				}
+
+				return
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+}
+
+func cableReadyMessage(ops ...map[string]interface{}) map[string]interface{} {
+	operations := make([]interface{}, len(ops))
+	for i, op := range ops {
+		operations[i] = op
+	}
+
+	return map[string]interface{}{"cableReady": true, "operations": operations}
+}
+
+func reflexServerConfig() mockserver.Config {
+	return mockserver.Config{
+		Channels: map[string]*mockserver.Channel{"StimulusReflex::Channel": {}},
+	}
+}
+
+func TestReflex(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, reflexServerConfig())
+
+	respondToReflex(t, server, "r-1", func(id string) []interface{} {
+		return []interface{}{
+			map[string]interface{}{"text": "unrelated"},
+			cableReadyMessage(map[string]interface{}{"operation": "consoleLog", "message": "started", "reflexId": id}),
+			cableReadyMessage(map[string]interface{}{"operation": "morph", "selector": "#other", "stimulusReflex": map[string]interface{}{"reflexId": "other", "morph": "page"}}),
+			cableReadyMessage(
+				map[string]interface{}{"operation": "morph", "selector": "#counter", "stimulusReflex": map[string]interface{}{"reflexId": id, "morph": "page"}},
+				map[string]interface{}{"operation": "morph", "selector": "#total", "stimulusReflex": map[string]interface{}{"reflexId": id, "morph": "page"}},
+			),
+		}
+	})
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("StimulusReflex::Channel");
+
+		const result = channel.reflex("Counter#increment", [1], { reflexId: "r-1", timeoutMs: 1000 });
+
+		// Unrelated messages are kept in the inbox
+		const unrelated = channel.receive({ text: "unrelated" });
+		const other = channel.receive(cable.cableReady({ selector: "#other" }));
+
+		JSON.stringify({
+			operations: result.operations.map((op) => op.operation + ":" + op.selector),
+			error: result.error,
+			unrelated: unrelated.text,
+			other: !!other,
+		})
+	`)
+
+	assert.JSONEq(t, `{
+		"operations": ["consoleLog:undefined", "morph:#counter", "morph:#total"],
+		"error": "",
+		"unrelated": "unrelated",
+		"other": true
+	}`, val.String())
+
+	durations := ts.requireMetric(t, "cable_reflex_duration", map[string]string{"reflex": "Counter#increment"})
+	assert.Len(t, durations, 1)
+}
+
+func TestReflexServerMessage(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, reflexServerConfig())
+
+	respondToReflex(t, server, "r-1", func(id string) []interface{} {
+		return []interface{}{
+			cableReadyMessage(map[string]interface{}{
+				"operation": "dispatchEvent",
+				"name":      "stimulus-reflex:server-message",
+				"detail": map[string]interface{}{
+					"reflexId":       id,
+					"stimulusReflex": map[string]interface{}{"serverMessage": map[string]interface{}{"subject": "halted"}},
+				},
+			}),
+		}
+	})
+
+	val := ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("StimulusReflex::Channel");
+
+		channel.reflex("Counter#increment", [], { reflexId: "r-1", timeoutMs: 1000 }).error
+	`)
+
+	assert.Equal(t, "halted", val.String())
+
+	// Reflexes are not finalized by intermediate operations
+	respondToReflex(t, server, "r-2", func(id string) []interface{} {
+		return []interface{}{
+			cableReadyMessage(map[string]interface{}{"operation": "consoleLog", "reflexId": id}),
+		}
+	})
+
+	result := ts.run(t, `channel.reflex("Counter#increment", [], { reflexId: "r-2", timeoutMs: 200 })`)
+	assert.True(t, sobek.IsNull(result))
+}
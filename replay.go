@@ -0,0 +1,736 @@
+package cable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	defaultReplayWaitMs = 1000
+
+	// replayCollectInterval is the interval between collecting received messages from the channels inboxes during the replay
+	// (so inboxes never fill up and block the connection read loop)
+	replayCollectInterval = 10 * time.Millisecond
+
+	// maxReplayDivergences is the max number of divergences included into the report
+	maxReplayDivergences = 20
+
+	replayResultMatched     = "matched"
+	replayResultMissing     = "missing"
+	replayResultUnexpected  = "unexpected"
+	replayResultAckMismatch = "ack_mismatch"
+)
+
+type replayOptions struct {
+	URL            string                 `json:"url"`
+	Speed          float64                `json:"speed"`
+	Vary           map[string]interface{} `json:"vary"`
+	Ignore         []string               `json:"ignore"`
+	Session        string                 `json:"session"`
+	WaitMs         *int                   `json:"waitMs"`
+	ConnectOptions json.RawMessage        `json:"connectOptions"`
+}
+
+// ReplayReport contains the results of the transcript replay
+type ReplayReport struct {
+	URL     string `js:"url"`
+	Session string `js:"session"`
+	// Sent is the number of replayed commands
+	Sent int `js:"sent"`
+	// Expected is the number of channel messages in the transcript
+	Expected int `js:"expected"`
+	// Received is the number of channel messages received during the replay
+	Received      int `js:"received"`
+	Matched       int `js:"matched"`
+	Missing       int `js:"missing"`
+	Unexpected    int `js:"unexpected"`
+	AckMismatches int `js:"ackMismatches"`
+	// Divergence is the share of diverged messages and subscription acks
+	Divergence  float64             `js:"divergence"`
+	Divergences []*ReplayDivergence `js:"divergences"`
+	Duration    int64               `js:"duration"`
+	Error       string              `js:"error"`
+}
+
+// ReplayDivergence describes the message (or the subscription ack) which differs from the transcript
+type ReplayDivergence struct {
+	Kind       string      `js:"kind"`
+	Identifier string      `js:"identifier"`
+	Message    interface{} `js:"message"`
+}
+
+// replayScript is the parsed transcript session
+type replayScript struct {
+	url      string
+	session  string
+	commands []*replayCommand
+	expected []*replayMessage
+	// acks contains the subscription results by identifier
+	acks map[string]bool
+	// duration is the offset of the last entry
+	duration time.Duration
+}
+
+type replayCommand struct {
+	offset time.Duration
+	msg    *cableMsg
+}
+
+type replayMessage struct {
+	offset     time.Duration
+	identifier string
+	message    interface{}
+}
+
+// replayEntry is the transcript entry with the message left undecoded
+type replayEntry struct {
+	Timestamp  time.Time       `json:"timestamp"`
+	Session    string          `json:"session"`
+	Direction  string          `json:"direction"`
+	Identifier string          `json:"identifier"`
+	Message    json.RawMessage `json:"message"`
+	Raw        bool            `json:"raw"`
+	URL        string          `json:"url"`
+}
+
+// harFile contains the parts of HAR archives used for replaying (WebSocket messages as recorded by Chrome DevTools)
+type harFile struct {
+	Log *struct {
+		Entries []struct {
+			Request struct {
+				URL string `json:"url"`
+			} `json:"request"`
+			WebSocketMessages []struct {
+				Type   string  `json:"type"`
+				Time   float64 `json:"time"`
+				Opcode int     `json:"opcode"`
+				Data   string  `json:"data"`
+			} `json:"_webSocketMessages"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// replayScripts caches parsed transcripts (all the VUs usually replay the same files)
+var replayScripts = newCompiledCache(maxCompiledCacheSize)
+
+// Replay re-issues subscribe and perform commands from the transcript (recorded via the `record` option or a HAR file)
+// keeping the original timing (scaled by the speed factor), and compares received messages against the transcript.
+func (c *Cable) Replay(transcriptPath string, optsIn sobek.Value) (*ReplayReport, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, errCableInInitContext
+	}
+
+	var opts replayOptions
+	if err := decodeOptions(c.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	cOpts, err := decodeConnectOptions(opts.ConnectOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if pusher, _ := cOpts.pusher(); pusher {
+		return nil, fmt.Errorf("replaying is only supported for the Action Cable protocol")
+	}
+
+	if opts.Speed < 0 {
+		return nil, fmt.Errorf("speed must be positive, got %v", opts.Speed)
+	}
+
+	if opts.Speed == 0 {
+		opts.Speed = 1
+	}
+
+	wait := defaultReplayWaitMs
+	if opts.WaitMs != nil {
+		wait = *opts.WaitMs
+	}
+
+	script, err := loadReplayScript(transcriptPath, opts.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	cableUrl := opts.URL
+	if cableUrl == "" {
+		cableUrl = script.url
+	}
+
+	if cableUrl == "" {
+		return nil, fmt.Errorf("transcript %s doesn't contain the connection URL, please, provide the url option", transcriptPath)
+	}
+
+	r := &replayer{
+		cable:  c,
+		script: script,
+		speed:  opts.Speed,
+		vary:   resolveReplayVary(opts.Vary, state.VUID, state.Iteration),
+		// Vary fields and the client-side receive timestamp are volatile
+		ignore:   append(append(append([]string(nil), opts.Ignore...), sortedKeys(opts.Vary)...), "__timestamp__"),
+		report:   &ReplayReport{URL: cableUrl, Session: script.session},
+		ids:      make(map[string]string),
+		received: make(map[string][]*cableMsg),
+	}
+
+	logger := createLogger(state, cOpts)
+	r.start = time.Now()
+
+	client, err := c.connect(cableUrl, cOpts, logger)
+	if err != nil {
+		r.report.Error = err.Error()
+		return r.report, nil
+	}
+
+	defer client.Disconnect()
+
+	r.client = client
+
+	if err := r.run(time.Duration(wait) * time.Millisecond); err != nil {
+		r.report.Error = err.Error()
+	}
+
+	r.compare()
+
+	r.report.Duration = time.Since(r.start).Milliseconds()
+
+	return r.report, nil
+}
+
+// replayer replays a single transcript session
+type replayer struct {
+	cable  *Cable
+	client *Client
+	script *replayScript
+	speed  float64
+	vary   map[string]interface{}
+	ignore []string
+	start  time.Time
+	report *ReplayReport
+
+	// ids maps the transcript identifiers to the replayed (varied) ones
+	ids map[string]string
+	// received contains the messages collected from the channels by the transcript identifiers
+	received map[string][]*cableMsg
+}
+
+func (r *replayer) run(wait time.Duration) error {
+	for _, cmd := range r.script.commands {
+		if !r.sleepUntil(r.at(cmd.offset)) {
+			return r.cable.vu.Context().Err()
+		}
+
+		if err := r.send(cmd.msg); err != nil {
+			return err
+		}
+
+		r.report.Sent++
+
+		r.collect()
+	}
+
+	// Wait for the rest of the transcript messages to arrive
+	r.sleepUntil(r.at(r.script.duration).Add(wait))
+
+	return nil
+}
+
+func (r *replayer) send(orig *cableMsg) error {
+	msg := *orig
+	msg.Identifier = r.identifier(orig.Identifier)
+
+	if msg.Data != "" {
+		msg.Data = r.varyJSON(msg.Data)
+	}
+
+	if msg.Command == "subscribe" {
+		_, err := r.client.subscribeIdentifier(msg.Identifier, replayChannelName(msg.Identifier), nil)
+		return err
+	}
+
+	return r.client.send(&msg)
+}
+
+// identifier returns the varied identifier (the same one for all the commands of the session)
+func (r *replayer) identifier(orig string) string {
+	if orig == "" {
+		return ""
+	}
+
+	if id, ok := r.ids[orig]; ok {
+		return id
+	}
+
+	id := r.varyJSON(orig)
+	r.ids[orig] = id
+
+	return id
+}
+
+// varyJSON substitutes VU-specific fields in the encoded JSON object; the original string is returned if nothing has changed
+func (r *replayer) varyJSON(raw string) string {
+	if len(r.vary) == 0 {
+		return raw
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		return raw
+	}
+
+	changed := false
+	for path, val := range r.vary {
+		if replaceAtPath(obj, path, val) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return raw
+	}
+
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		return raw
+	}
+
+	return string(encoded)
+}
+
+// collect moves the messages received so far from the channels inboxes to the replayer
+func (r *replayer) collect() {
+	r.client.mu.Lock()
+	defer r.client.mu.Unlock()
+
+	for orig, id := range r.ids {
+		if ch := r.client.channels[id]; ch != nil {
+			r.received[orig] = append(r.received[orig], ch.drain()...)
+		}
+	}
+}
+
+// compare matches received channel messages and subscription acks against the transcript
+func (r *replayer) compare() {
+	r.collect()
+
+	received := r.received
+	acks := make(map[string]*bool)
+
+	r.client.mu.Lock()
+	for orig, id := range r.ids {
+		ch := r.client.channels[id]
+		if ch == nil {
+			continue
+		}
+
+		select {
+		case val := <-ch.confCh:
+			acks[orig] = &val
+		default:
+		}
+	}
+	r.client.mu.Unlock()
+
+	expected := make(map[string][]*replayMessage)
+	for _, msg := range r.script.expected {
+		expected[msg.identifier] = append(expected[msg.identifier], msg)
+	}
+
+	for _, orig := range sortedKeys(r.ids) {
+		channel := replayChannelName(orig)
+
+		if want, ok := r.script.acks[orig]; ok && (acks[orig] == nil || *acks[orig] != want) {
+			r.report.AckMismatches++
+			r.diverged(replayResultAckMismatch, channel, orig, nil)
+		}
+
+		msgs := received[orig]
+		r.report.Received += len(msgs)
+
+		actual := make([]interface{}, len(msgs))
+		used := make([]bool, len(msgs))
+		for i, msg := range msgs {
+			actual[i] = normalizeMessage(msg.Message, r.ignore)
+		}
+
+		for _, exp := range expected[orig] {
+			want := normalizeMessage(exp.message, r.ignore)
+			found := -1
+
+			for i := range actual {
+				if !used[i] && reflect.DeepEqual(want, actual[i]) {
+					found = i
+					break
+				}
+			}
+
+			if found < 0 {
+				r.report.Missing++
+				r.diverged(replayResultMissing, channel, orig, exp.message)
+				continue
+			}
+
+			used[found] = true
+			r.report.Matched++
+			r.track(replayResultMatched, channel, 0)
+			r.trackLag(channel, msgs[found].receivedAt.Sub(r.at(exp.offset)))
+		}
+
+		for i, msg := range msgs {
+			if !used[i] {
+				r.report.Unexpected++
+				r.diverged(replayResultUnexpected, channel, orig, msg.Message)
+			}
+		}
+	}
+
+	for _, msg := range r.script.expected {
+		if _, ok := r.ids[msg.identifier]; !ok {
+			r.report.Missing++
+			r.diverged(replayResultMissing, replayChannelName(msg.identifier), msg.identifier, msg.message)
+		}
+	}
+
+	r.report.Expected = len(r.script.expected)
+
+	total := r.report.Matched + r.report.Missing + r.report.Unexpected + r.report.AckMismatches
+	if total > 0 {
+		r.report.Divergence = float64(total-r.report.Matched) / float64(total)
+	}
+}
+
+func (r *replayer) diverged(kind string, channel string, identifier string, message interface{}) {
+	r.track(kind, channel, 1)
+
+	if len(r.report.Divergences) < maxReplayDivergences {
+		r.report.Divergences = append(r.report.Divergences, &ReplayDivergence{Kind: kind, Identifier: identifier, Message: message})
+	}
+}
+
+func (r *replayer) track(result string, channel string, value float64) {
+	state := r.cable.vu.State()
+
+	metrics.PushIfNotDone(r.cable.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: r.cable.metrics.ReplayDivergence,
+			Tags:   state.Tags.GetCurrentValues().Tags.With("channel", channel).With("result", result),
+		},
+		Time:  time.Now(),
+		Value: value,
+	})
+}
+
+func (r *replayer) trackLag(channel string, lag time.Duration) {
+	state := r.cable.vu.State()
+
+	metrics.PushIfNotDone(r.cable.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: r.cable.metrics.ReplayLag,
+			Tags:   state.Tags.GetCurrentValues().Tags.With("channel", channel),
+		},
+		Time:  time.Now(),
+		Value: metrics.D(lag),
+	})
+}
+
+// at returns the replay time for the transcript offset
+func (r *replayer) at(offset time.Duration) time.Time {
+	return r.start.Add(time.Duration(float64(offset) / r.speed))
+}
+
+// sleepUntil collects received messages while waiting; returns false if the VU context is done before the deadline
+func (r *replayer) sleepUntil(deadline time.Time) bool {
+	d := time.Until(deadline)
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(replayCollectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case <-ticker.C:
+			r.collect()
+		case <-r.cable.vu.Context().Done():
+			return false
+		}
+	}
+}
+
+// drain returns all the messages from the channel inbox without waiting
+func (ch *Channel) drain() []*cableMsg {
+	msgs := ch.stash
+	ch.stash = nil
+
+	for {
+		select {
+		case msg := <-ch.readCh:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+func loadReplayScript(path string, session string) (*replayScript, error) {
+	key := path + "\x00" + session
+
+	if script, ok := replayScripts.get(key); ok {
+		return script.(*replayScript), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	var har harFile
+
+	var script *replayScript
+	if err := json.Unmarshal(data, &har); err == nil && har.Log != nil {
+		script, err = parseHARScript(&har)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		script, err = parseTranscriptScript(data, session)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	replayScripts.put(key, script)
+
+	return script, nil
+}
+
+func parseTranscriptScript(data []byte, session string) (*replayScript, error) {
+	script := &replayScript{acks: make(map[string]bool)}
+
+	var start time.Time
+	selected := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry replayEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid transcript entry at line %d: %w", line, err)
+		}
+
+		// Use the first session if none specified
+		if !selected && (session == "" || entry.Session == session) {
+			session = entry.Session
+			start = entry.Timestamp
+			selected = true
+		}
+
+		if !selected || entry.Session != session {
+			continue
+		}
+
+		offset := entry.Timestamp.Sub(start)
+		script.duration = offset
+
+		switch entry.Direction {
+		case recordDirectionConnect:
+			script.url = entry.URL
+		case recordDirectionOut:
+			// Raw frames are not replayed
+			if entry.Raw {
+				continue
+			}
+
+			msg := &cableMsg{}
+			if err := json.Unmarshal(entry.Message, msg); err != nil {
+				return nil, fmt.Errorf("invalid transcript message at line %d: %w", line, err)
+			}
+
+			script.addCommand(offset, msg)
+		case recordDirectionIn:
+			msg := &cableMsg{}
+			if err := json.Unmarshal(entry.Message, msg); err != nil {
+				return nil, fmt.Errorf("invalid transcript message at line %d: %w", line, err)
+			}
+
+			script.addIncoming(offset, msg)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	if !selected {
+		return nil, fmt.Errorf("transcript session not found: %s", session)
+	}
+
+	script.session = session
+
+	return script, nil
+}
+
+// parseHARScript uses the first WebSocket entry of the archive
+func parseHARScript(har *harFile) (*replayScript, error) {
+	for _, entry := range har.Log.Entries {
+		if len(entry.WebSocketMessages) == 0 {
+			continue
+		}
+
+		script := &replayScript{url: entry.Request.URL, acks: make(map[string]bool)}
+		start := entry.WebSocketMessages[0].Time
+
+		for _, frame := range entry.WebSocketMessages {
+			// Binary frames (msgpack, protobuf) are not supported
+			if frame.Opcode != 0 && frame.Opcode != 1 {
+				continue
+			}
+
+			offset := time.Duration((frame.Time - start) * float64(time.Second))
+			script.duration = offset
+
+			msg := &cableMsg{}
+			if err := json.Unmarshal([]byte(frame.Data), msg); err != nil {
+				continue
+			}
+
+			switch frame.Type {
+			case "send":
+				script.addCommand(offset, msg)
+			case "receive":
+				script.addIncoming(offset, msg)
+			}
+		}
+
+		return script, nil
+	}
+
+	return nil, fmt.Errorf("HAR file doesn't contain WebSocket messages")
+}
+
+func (s *replayScript) addCommand(offset time.Duration, msg *cableMsg) {
+	// Pongs and other client-level commands are not replayed
+	if msg.Identifier == "" {
+		return
+	}
+
+	s.commands = append(s.commands, &replayCommand{offset: offset, msg: msg})
+}
+
+func (s *replayScript) addIncoming(offset time.Duration, msg *cableMsg) {
+	switch msg.Type {
+	case "confirm_subscription":
+		s.acks[msg.Identifier] = true
+	case "reject_subscription":
+		s.acks[msg.Identifier] = false
+	case "", "message", "no_type":
+		if msg.Identifier != "" && msg.Message != nil {
+			s.expected = append(s.expected, &replayMessage{offset: offset, identifier: msg.Identifier, message: msg.Message})
+		}
+	}
+}
+
+// resolveReplayVary interpolates {vu}, {iter} and {uuid} in vary values.
+// Values consisting of a single {vu} or {iter} token are converted to numbers.
+func resolveReplayVary(vary map[string]interface{}, vuID uint64, iter int64) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(vary))
+
+	for path, val := range vary {
+		str, ok := val.(string)
+		if !ok {
+			resolved[path] = val
+			continue
+		}
+
+		switch str {
+		case "{vu}":
+			resolved[path] = vuID
+			continue
+		case "{iter}":
+			resolved[path] = iter
+			continue
+		}
+
+		str = strings.ReplaceAll(str, "{vu}", strconv.FormatUint(vuID, 10))
+		str = strings.ReplaceAll(str, "{iter}", strconv.FormatInt(iter, 10))
+
+		if strings.Contains(str, "{uuid}") {
+			uuid, _ := randomUUID()
+			str = strings.ReplaceAll(str, "{uuid}", uuid)
+		}
+
+		resolved[path] = str
+	}
+
+	return resolved
+}
+
+// replaceAtPath sets the value at the dotted path if the path exists
+func replaceAtPath(data map[string]interface{}, path string, val interface{}) bool {
+	keys := strings.Split(path, ".")
+	obj := data
+
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		obj = next
+	}
+
+	last := keys[len(keys)-1]
+	if _, ok := obj[last]; !ok {
+		return false
+	}
+
+	obj[last] = val
+
+	return true
+}
+
+func replayChannelName(identifier string) string {
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(identifier), &params); err != nil {
+		return ""
+	}
+
+	name, _ := params["channel"].(string)
+	return name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
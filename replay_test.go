@@ -0,0 +1,191 @@
+package cable
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anycable/xk6-cable/mockserver"
+)
+
+// recordSession records the script to a new transcript and waits for the transcript to be closed
+func recordSession(t *testing.T, ts *testState, code string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	require.NoError(t, ts.VU.Runtime().Set("TRANSCRIPT", path))
+
+	ts.run(t, code)
+
+	require.Eventually(t, func() bool {
+		entries := readTranscript(t, path)
+		return len(entries) > 0 && entries[len(entries)-1]["direction"] == recordDirectionClose
+	}, time.Second, 10*time.Millisecond)
+
+	return path
+}
+
+func TestReplayTranscript(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, echoServerConfig())
+
+	recordSession(t, ts, `
+		const client = cable.connect(CABLE_URL, { record: TRANSCRIPT });
+		const channel = client.subscribe("EchoChannel");
+		channel.perform("echo", { text: "hello" });
+		channel.receive();
+		channel.perform("echo", { text: "bye" });
+		channel.receive();
+		client.disconnect();
+	`)
+
+	report := ts.run(t, `cable.replay(TRANSCRIPT, { speed: 2, waitMs: 200 })`).Export().(*ReplayReport)
+
+	assert.Empty(t, report.Error)
+	assert.Equal(t, server.URL, report.URL)
+	assert.NotEmpty(t, report.Session)
+	assert.Equal(t, 3, report.Sent)
+	assert.Equal(t, 2, report.Expected)
+	assert.Equal(t, 2, report.Received)
+	assert.Equal(t, 2, report.Matched)
+	assert.Zero(t, report.Divergence)
+	assert.Empty(t, report.Divergences)
+
+	matched := ts.metricSamples("cable_replay_divergence", map[string]string{"channel": "EchoChannel", "result": "matched"})
+	assert.Len(t, matched, 2)
+	assert.Equal(t, 0.0, matched[0].Value)
+
+	ts.requireMetric(t, "cable_replay_lag", map[string]string{"channel": "EchoChannel"})
+}
+
+func TestReplayManyMessages(t *testing.T) {
+	// More messages than the channel inbox can hold
+	const count = 2200
+
+	broadcasts := make([]mockserver.Broadcast, count)
+	for i := range broadcasts {
+		broadcasts[i] = mockserver.Broadcast{Message: map[string]interface{}{"n": i}}
+	}
+
+	ts := newTestState(t)
+	ts.startMockServer(t, mockserver.Config{
+		Channels: map[string]*mockserver.Channel{"FeedChannel": {Broadcasts: broadcasts}},
+	})
+
+	recordSession(t, ts, `
+		const client = cable.connect(CABLE_URL, { record: TRANSCRIPT });
+		const channel = client.subscribe("FeedChannel");
+		channel.receiveN(`+strconv.Itoa(count)+`);
+		client.disconnect();
+	`)
+
+	// Move recorded samples out of the (bounded) samples channel
+	ts.metricSamples("", nil)
+
+	report := ts.run(t, `cable.replay(TRANSCRIPT, { waitMs: 500 })`).Export().(*ReplayReport)
+
+	assert.Empty(t, report.Error)
+	assert.Equal(t, count, report.Expected)
+	assert.Equal(t, count, report.Received)
+	assert.Equal(t, count, report.Matched)
+	assert.Zero(t, report.Divergence)
+}
+
+func TestReplayVary(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, echoServerConfig())
+
+	recordSession(t, ts, `
+		const client = cable.connect(CABLE_URL, { record: TRANSCRIPT });
+		const channel = client.subscribe("EchoChannel", { user_id: 1 });
+		channel.perform("echo", { user_id: 1, profile: { name: "jack" } });
+		channel.receive();
+		client.disconnect();
+	`)
+
+	ts.VU.StateField.VUID = 42
+
+	report := ts.run(t, `cable.replay(TRANSCRIPT, {
+		waitMs: 100,
+		vary: { user_id: "{vu}", "profile.name": "user-{vu}" },
+	})`).Export().(*ReplayReport)
+
+	assert.Equal(t, 1, report.Matched)
+	assert.Zero(t, report.Divergence)
+
+	commands := server.Commands()
+	replayed := commands[len(commands)-2:]
+
+	assert.Equal(t, `{"channel":"EchoChannel","user_id":42}`, replayed[0].Identifier)
+	assert.JSONEq(t, `{"action":"echo","user_id":42,"profile":{"name":"user-42"}}`, replayed[1].Data)
+}
+
+func TestReplayDivergence(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	recordSession(t, ts, `
+		const client = cable.connect(CABLE_URL, { record: TRANSCRIPT });
+		const channel = client.subscribe("EchoChannel");
+		channel.perform("echo", { text: "hello" });
+		channel.receive();
+		client.disconnect();
+	`)
+
+	// The echo channel is now rejected
+	ts.startMockServer(t, mockserver.Config{Channels: map[string]*mockserver.Channel{"EchoChannel": {Reject: true}}})
+
+	report := ts.run(t, `cable.replay(TRANSCRIPT, { url: CABLE_URL, waitMs: 100 })`).Export().(*ReplayReport)
+
+	assert.Equal(t, 1, report.Missing)
+	assert.Equal(t, 1, report.AckMismatches)
+	assert.Equal(t, 1.0, report.Divergence)
+	require.Len(t, report.Divergences, 2)
+	assert.Equal(t, replayResultAckMismatch, report.Divergences[0].Kind)
+	assert.Equal(t, replayResultMissing, report.Divergences[1].Kind)
+
+	missing := ts.requireMetric(t, "cable_replay_divergence", map[string]string{"channel": "EchoChannel", "result": "missing"})
+	assert.Equal(t, 1.0, missing[0].Value)
+}
+
+func TestReplayHAR(t *testing.T) {
+	ts := newTestState(t)
+	server := ts.startMockServer(t, echoServerConfig())
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	require.NoError(t, os.WriteFile(path, []byte(`{"log":{"entries":[
+		{"request":{"url":"http://example.com/"}},
+		{"request":{"url":"ws://example.com/cable"},"_webSocketMessages":[
+			{"type":"receive","time":1700000000.0,"opcode":1,"data":"{\"type\":\"welcome\"}"},
+			{"type":"send","time":1700000000.05,"opcode":1,"data":"{\"command\":\"subscribe\",\"identifier\":\"{\\\"channel\\\":\\\"EchoChannel\\\"}\"}"},
+			{"type":"receive","time":1700000000.06,"opcode":1,"data":"{\"type\":\"confirm_subscription\",\"identifier\":\"{\\\"channel\\\":\\\"EchoChannel\\\"}\"}"},
+			{"type":"send","time":1700000000.1,"opcode":1,"data":"{\"command\":\"message\",\"identifier\":\"{\\\"channel\\\":\\\"EchoChannel\\\"}\",\"data\":\"{\\\"action\\\":\\\"echo\\\",\\\"n\\\":1}\"}"},
+			{"type":"receive","time":1700000000.11,"opcode":1,"data":"{\"identifier\":\"{\\\"channel\\\":\\\"EchoChannel\\\"}\",\"message\":{\"action\":\"echo\",\"n\":1}}"}
+		]}
+	]}}`), 0o600))
+
+	require.NoError(t, ts.VU.Runtime().Set("TRANSCRIPT", path))
+
+	report := ts.run(t, `cable.replay(TRANSCRIPT, { url: CABLE_URL, waitMs: 100 })`).Export().(*ReplayReport)
+
+	assert.Empty(t, report.Error)
+	assert.Equal(t, server.URL, report.URL)
+	assert.Equal(t, 2, report.Sent)
+	assert.Equal(t, 1, report.Matched)
+	assert.Zero(t, report.Divergence)
+}
+
+func TestReplayInvalidOptions(t *testing.T) {
+	ts := newTestState(t)
+
+	_, err := ts.VU.Runtime().RunString(`cable.replay("missing.jsonl", {})`)
+	require.ErrorContains(t, err, "failed to read transcript")
+
+	_, err = ts.VU.Runtime().RunString(`cable.replay("missing.jsonl", { speed: -1 })`)
+	require.ErrorContains(t, err, "speed must be positive")
+}
@@ -0,0 +1,104 @@
+package cable
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+)
+
+const defaultRequestIDField = "request_id"
+
+type requestOptions struct {
+	TimeoutMs int    `json:"timeoutMs"`
+	IDField   string `json:"idField"`
+}
+
+// Request performs the action with a unique correlation ID and waits for the response carrying the same ID.
+// Other messages received in the meantime are kept in the inbox.
+// Returns null if no response received in time.
+func (ch *Channel) Request(action string, attr sobek.Value, optsIn sobek.Value) (interface{}, error) {
+	var opts requestOptions
+	if err := decodeOptions(ch.client.vu.Runtime(), optsIn, &opts); err != nil {
+		return nil, err
+	}
+
+	idField := opts.IDField
+	if idField == "" {
+		idField = defaultRequestIDField
+	}
+
+	timeout := ch.client.recTimeout
+	if opts.TimeoutMs > 0 {
+		timeout = time.Duration(opts.TimeoutMs) * time.Millisecond
+	}
+
+	obj := make(map[string]interface{})
+	if attr != nil && !sobek.IsUndefined(attr) && !sobek.IsNull(attr) {
+		obj = attr.ToObject(ch.client.vu.Runtime()).Export().(map[string]interface{})
+	}
+
+	requestID, err := randomUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	obj[idField] = requestID
+
+	start := time.Now()
+
+	if err := ch.perform(action, obj); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var skipped []*cableMsg
+	defer func() { ch.unshift(skipped) }()
+
+	for {
+		msg := ch.next(timer.C)
+		if msg == nil {
+			ch.logger.Warnf("request %s timeout exceeded; consider increasing timeoutMs option", action)
+			return nil, nil
+		}
+
+		if !matchRequestID(msg.Message, idField, requestID) {
+			skipped = append(skipped, msg)
+			continue
+		}
+
+		ch.trackRequest(action, msg.receivedAt.Sub(start), msg.receivedAt)
+
+		return msg.Message, nil
+	}
+}
+
+func (ch *Channel) trackRequest(action string, d time.Duration, when time.Time) {
+	c := ch.client
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.RequestDuration,
+			Tags:   c.sampleTags.With("action", action),
+		},
+		Time:  when,
+		Value: metrics.D(d),
+	})
+}
+
+func matchRequestID(msg interface{}, idField string, requestID string) bool {
+	obj, ok := msg.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	val, ok := obj[idField]
+	if !ok {
+		return false
+	}
+
+	return fmt.Sprintf("%v", val) == requestID
+}
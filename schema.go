@@ -0,0 +1,176 @@
+package cable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"go.k6.io/k6/metrics"
+)
+
+// compiledSchemas caches compiled schemas by file path or contents (all the VUs usually use the same schemas)
+var compiledSchemas = newCompiledCache(maxCompiledCacheSize)
+
+// channelSchema validates incoming channel messages
+type channelSchema struct {
+	schema *jsonschema.Schema
+
+	// logLimit is the max number of violations to log
+	logLimit int32
+	logged   int32
+}
+
+// schemaViolation is the failed validation with the JSON pointer to the invalid value
+type schemaViolation struct {
+	Pointer string
+	Message string
+}
+
+// newChannelSchema compiles the schema provided as an object or a path to the schema file
+// (relative paths are resolved against the script directory, the same way as by open())
+func newChannelSchema(raw json.RawMessage, logLimit int, scriptDir string) (*channelSchema, error) {
+	schema, err := compileSchema(raw, scriptDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &channelSchema{schema: schema, logLimit: int32(logLimit)}, nil
+}
+
+func compileSchema(raw json.RawMessage, scriptDir string) (*jsonschema.Schema, error) {
+	key := string(raw)
+
+	var path string
+
+	isPath := json.Unmarshal(raw, &path) == nil
+	if isPath {
+		var err error
+		if path, err = resolveScriptPath(path, scriptDir); err != nil {
+			return nil, err
+		}
+
+		key = path
+	}
+
+	if schema, ok := compiledSchemas.get(key); ok {
+		return schema.(*jsonschema.Schema), nil
+	}
+
+	var (
+		schema *jsonschema.Schema
+		err    error
+	)
+
+	if isPath {
+		schema, err = jsonschema.Compile(path)
+	} else {
+		schema, err = jsonschema.CompileString("schema.json", key)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	compiledSchemas.put(key, schema)
+
+	return schema, nil
+}
+
+// resolveScriptPath returns the absolute path for the file path relative to the script directory
+// (or the current working directory if the script directory is unknown)
+func resolveScriptPath(path string, scriptDir string) (string, error) {
+	if scriptDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(scriptDir, path)
+	}
+
+	return filepath.Abs(path)
+}
+
+// validate returns the list of violations (empty if the message is valid)
+func (s *channelSchema) validate(msg interface{}) []schemaViolation {
+	// Pass the message through JSON to use the same types for all codecs;
+	// the client-side receive timestamp is not a part of the message
+	value := normalizeMessage(msg, []string{"__timestamp__"})
+
+	err := s.schema.Validate(value)
+	if err == nil {
+		return nil
+	}
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return []schemaViolation{{Pointer: "/", Message: err.Error()}}
+	}
+
+	var violations []schemaViolation
+	collectSchemaViolations(verr, &violations)
+
+	return violations
+}
+
+// collectSchemaViolations returns the leaf validation errors (the most specific ones)
+func collectSchemaViolations(err *jsonschema.ValidationError, violations *[]schemaViolation) {
+	if len(err.Causes) == 0 {
+		pointer := err.InstanceLocation
+		if pointer == "" {
+			pointer = "/"
+		}
+
+		*violations = append(*violations, schemaViolation{Pointer: pointer, Message: err.Message})
+		return
+	}
+
+	for _, cause := range err.Causes {
+		collectSchemaViolations(cause, violations)
+	}
+}
+
+// shouldLog returns true until the log limit is reached
+func (s *channelSchema) shouldLog() bool {
+	if s.logLimit <= 0 {
+		return false
+	}
+
+	return atomic.AddInt32(&s.logged, 1) <= s.logLimit
+}
+
+// validateSchema validates the message and tracks the result
+func (ch *Channel) validateSchema(msg *cableMsg) {
+	violations := ch.schema.validate(msg.Message)
+
+	c := ch.client
+	tags := c.sampleTags
+	if name := c.channelName(ch.identifier); name != "" {
+		tags = tags.With("channel", name)
+	}
+
+	metric := c.metrics.SchemaValidMessages
+	if len(violations) > 0 {
+		metric = c.metrics.SchemaInvalidMessages
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   tags,
+		},
+		Time:  time.Now(),
+		Value: 1,
+	})
+
+	if len(violations) == 0 || !ch.schema.shouldLog() {
+		return
+	}
+
+	details := make([]string, len(violations))
+	for i, v := range violations {
+		details[i] = fmt.Sprintf("%s: %s", v.Pointer, v.Message)
+	}
+
+	ch.logger.Warnf("message for %s doesn't conform to the schema: %s", ch.identifier, strings.Join(details, "; "))
+}
@@ -0,0 +1,111 @@
+package cable
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["action", "user"],
+	"properties": {
+		"action": { "type": "string" },
+		"user": {
+			"type": "object",
+			"required": ["id"],
+			"properties": { "id": { "type": "integer" }, "name": { "type": "string" } }
+		}
+	},
+	"additionalProperties": false
+}`
+
+func TestSubscribeWithSchema(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	require.NoError(t, ts.VU.Runtime().Set("SCHEMA", json.RawMessage(userSchema)))
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel", {}, { schema: JSON.parse(SCHEMA), schemaLogViolations: 1 });
+
+		channel.perform("echo", { user: { id: 1, name: "jack" } });
+		channel.perform("echo", { user: { id: "2" } });
+		channel.perform("echo", { user: { id: 3 }, extra: true });
+		channel.receiveN(3);
+	`)
+
+	valid := ts.requireMetric(t, "cable_schema_valid_messages", map[string]string{"channel": "EchoChannel"})
+	assert.Len(t, valid, 1)
+
+	invalid := ts.requireMetric(t, "cable_schema_invalid_messages", map[string]string{"channel": "EchoChannel"})
+	assert.Len(t, invalid, 2)
+}
+
+func TestSubscribeWithSchemaFileAndIgnoreReads(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	// Relative paths are resolved against the script directory
+	dir := t.TempDir()
+	ts.module.scriptDir = dir
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.json"), []byte(userSchema), 0o600))
+	require.NoError(t, ts.VU.Runtime().Set("SCHEMA_PATH", "user.json"))
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel", {}, { schema: SCHEMA_PATH });
+		channel.ignoreReads();
+
+		channel.perform("echo", { user: { id: 1 } });
+	`)
+
+	ts.requireMetric(t, "cable_schema_valid_messages", map[string]string{"channel": "EchoChannel"})
+}
+
+func TestSubscribeWithInvalidSchema(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	_, err := ts.VU.Runtime().RunString(`
+		const client = cable.connect(CABLE_URL);
+		client.subscribe("EchoChannel", {}, { schema: { type: "unknown" } });
+	`)
+	assert.ErrorContains(t, err, "invalid schema")
+
+	_, err = ts.VU.Runtime().RunString(`client.subscribe("EchoChannel", {}, { schemas: {} })`)
+	assert.ErrorContains(t, err, `unknown field "schemas"`)
+}
+
+func TestSchemaViolations(t *testing.T) {
+	schema, err := newChannelSchema(json.RawMessage(userSchema), 2, "")
+	require.NoError(t, err)
+
+	assert.Empty(t, schema.validate(map[string]interface{}{
+		"action":        "echo",
+		"user":          map[string]interface{}{"id": int8(1)},
+		"__timestamp__": int64(1700000000000),
+	}))
+
+	violations := schema.validate(map[string]interface{}{
+		"user":  map[string]interface{}{"id": "1", "name": 2},
+		"extra": 1,
+	})
+
+	pointers := make([]string, len(violations))
+	for i, v := range violations {
+		pointers[i] = v.Pointer
+	}
+
+	assert.ElementsMatch(t, []string{"/", "/", "/user/id", "/user/name"}, pointers)
+
+	assert.True(t, schema.shouldLog())
+	assert.True(t, schema.shouldLog())
+	assert.False(t, schema.shouldLog())
+}
@@ -0,0 +1,266 @@
+package cable
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	sequenceInOrder    = "in_order"
+	sequenceOutOfOrder = "out_of_order"
+	sequenceDuplicate  = "duplicate"
+	sequenceUntracked  = "untracked"
+
+	// maxSequenceGap is the max gap for which the missing sequence numbers are remembered
+	// (so late messages could be recognized as out-of-order)
+	maxSequenceGap = 10000
+
+	// maxReportedMissing is the max number of missing sequence numbers per key included into the report
+	maxReportedMissing = 100
+
+	// maxRememberedMissing is the max number of missing sequence numbers remembered by the tracker (for all keys);
+	// the oldest ones are forgotten first (and counted as duplicates if received later)
+	maxRememberedMissing = 10000
+)
+
+// SequenceReport contains the ordering stats of the channel messages
+type SequenceReport struct {
+	InOrder    int64 `js:"inOrder"`
+	OutOfOrder int64 `js:"outOfOrder"`
+	Duplicates int64 `js:"duplicates"`
+	// Missing is the number of sequence numbers skipped and not received so far
+	Missing int64 `js:"missing"`
+	// Untracked is the number of messages without a sequence number
+	Untracked int64 `js:"untracked"`
+	// Keys is the number of producers (sequence keys)
+	Keys int `js:"keys"`
+	// MissingSequences contains the first missing sequence numbers by keys
+	MissingSequences map[string][]int64 `js:"missingSequences"`
+}
+
+// sequenceTracker tracks sequence numbers of incoming messages (per key, if the key field is specified)
+type sequenceTracker struct {
+	field    string
+	keyField string
+	start    *int64
+
+	mu     sync.Mutex
+	keys   map[string]*sequenceState
+	report SequenceReport
+	// missingOrder contains remembered missing sequence numbers in the order they were added
+	missingOrder []missingSequence
+
+	// flushed guards the missing messages metric from being emitted more than once
+	flushed sync.Once
+}
+
+type sequenceState struct {
+	last    int64
+	missing map[int64]struct{}
+}
+
+type missingSequence struct {
+	state *sequenceState
+	seq   int64
+}
+
+func newSequenceTracker(field string, keyField string, start *int64) *sequenceTracker {
+	return &sequenceTracker{field: field, keyField: keyField, start: start, keys: make(map[string]*sequenceState)}
+}
+
+// track returns the result for the message (in_order, out_of_order, duplicate or untracked).
+// Messages skipped by gaps larger than maxSequenceGap are counted as duplicates if received later.
+func (t *sequenceTracker) track(msg interface{}) string {
+	raw, ok := lookupPath(msg, t.field)
+	if !ok {
+		return t.untracked()
+	}
+
+	num, ok := toFloat64(raw)
+	// Only integer sequence numbers are tracked
+	if !ok || num != math.Trunc(num) || num > math.MaxInt64 || num < math.MinInt64 {
+		return t.untracked()
+	}
+
+	seq := int64(num)
+
+	key := ""
+	if t.keyField != "" {
+		val, ok := lookupPath(msg, t.keyField)
+		if !ok {
+			return t.untracked()
+		}
+		key = fmt.Sprintf("%v", val)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.keys[key]
+	if state == nil {
+		state = &sequenceState{missing: make(map[int64]struct{})}
+		t.keys[key] = state
+
+		if t.start == nil {
+			state.last = seq
+			t.report.InOrder++
+			return sequenceInOrder
+		}
+
+		state.last = *t.start - 1
+	}
+
+	switch {
+	case seq > state.last:
+		gap := seq - state.last - 1
+
+		if gap <= maxSequenceGap {
+			for i := state.last + 1; i < seq; i++ {
+				t.remember(state, i)
+			}
+		}
+
+		state.last = seq
+		t.report.InOrder++
+		t.report.Missing += gap
+
+		return sequenceInOrder
+	default:
+		if _, ok := state.missing[seq]; ok {
+			delete(state.missing, seq)
+			t.report.OutOfOrder++
+			t.report.Missing--
+
+			return sequenceOutOfOrder
+		}
+
+		t.report.Duplicates++
+
+		return sequenceDuplicate
+	}
+}
+
+// remember adds the missing sequence number forgetting the oldest one if the limit is reached
+func (t *sequenceTracker) remember(state *sequenceState, seq int64) {
+	state.missing[seq] = struct{}{}
+	t.missingOrder = append(t.missingOrder, missingSequence{state, seq})
+
+	if len(t.missingOrder) <= maxRememberedMissing {
+		return
+	}
+
+	// Entries could be stale (if the message has been received since then), deleting is a no-op in this case
+	oldest := t.missingOrder[0]
+	t.missingOrder = t.missingOrder[1:]
+
+	delete(oldest.state.missing, oldest.seq)
+}
+
+func (t *sequenceTracker) untracked() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.report.Untracked++
+
+	return sequenceUntracked
+}
+
+func (t *sequenceTracker) summary() *SequenceReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := t.report
+	report.Keys = len(t.keys)
+	report.MissingSequences = make(map[string][]int64)
+
+	for key, state := range t.keys {
+		if len(state.missing) == 0 {
+			continue
+		}
+
+		missing := make([]int64, 0, len(state.missing))
+		for seq := range state.missing {
+			missing = append(missing, seq)
+		}
+
+		sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+
+		if len(missing) > maxReportedMissing {
+			missing = missing[:maxReportedMissing]
+		}
+
+		report.MissingSequences[key] = missing
+	}
+
+	return &report
+}
+
+// SequenceReport returns the ordering stats (requires the sequenceField subscribe option)
+func (ch *Channel) SequenceReport() (*SequenceReport, error) {
+	if ch.sequence == nil {
+		return nil, fmt.Errorf("sequence tracking is not enabled; use the sequenceField subscribe option")
+	}
+
+	return ch.sequence.summary(), nil
+}
+
+// trackSequence tracks the message sequence number and emits the corresponding metric
+func (ch *Channel) trackSequence(msg *cableMsg) {
+	result := ch.sequence.track(msg.Message)
+
+	var metric *metrics.Metric
+
+	switch result {
+	case sequenceInOrder:
+		metric = ch.client.metrics.SequenceInOrder
+	case sequenceOutOfOrder:
+		metric = ch.client.metrics.SequenceOutOfOrder
+	case sequenceDuplicate:
+		metric = ch.client.metrics.SequenceDuplicates
+	default:
+		return
+	}
+
+	ch.pushSequenceMetric(metric, 1, msg.receivedAt)
+}
+
+// flushSequenceMissing emits the number of messages missing by the time the connection is closed
+// (either via disconnect or when the VU context is done); the metric is emitted once
+func (ch *Channel) flushSequenceMissing() {
+	if ch.sequence == nil {
+		return
+	}
+
+	ch.sequence.flushed.Do(func() {
+		if missing := ch.sequence.summary().Missing; missing > 0 {
+			ch.client.pushOnClose(ch.sequenceSample(ch.client.metrics.SequenceMissing, float64(missing), time.Now()))
+		}
+	})
+}
+
+func (ch *Channel) pushSequenceMetric(metric *metrics.Metric, value float64, when time.Time) {
+	metrics.PushIfNotDone(ch.client.vu.Context(), ch.client.samplesOutput, ch.sequenceSample(metric, value, when))
+}
+
+func (ch *Channel) sequenceSample(metric *metrics.Metric, value float64, when time.Time) metrics.Sample {
+	c := ch.client
+
+	tags := c.sampleTags
+	if name := c.channelName(ch.identifier); name != "" {
+		tags = tags.With("channel", name)
+	}
+
+	return metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   tags,
+		},
+		Time:  when,
+		Value: value,
+	}
+}
@@ -0,0 +1,130 @@
+package cable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceTracking(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel", {}, { sequenceField: "meta.seq", sequenceKeyField: "producer" });
+		channel.ignoreReads();
+
+		for (const seq of [1, 2, 4, 3, 3, 7]) {
+			channel.perform("echo", { producer: "a", meta: { seq } });
+		}
+
+		channel.perform("echo", { producer: "b", meta: { seq: 10 } });
+		channel.perform("echo", { producer: "b", meta: { seq: 11 } });
+		channel.perform("echo", { text: "no sequence" });
+	`)
+
+	var report *SequenceReport
+
+	require.Eventually(t, func() bool {
+		report = ts.run(t, `channel.sequenceReport()`).Export().(*SequenceReport)
+		return report.Untracked == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.EqualValues(t, 6, report.InOrder)
+	assert.EqualValues(t, 1, report.OutOfOrder)
+	assert.EqualValues(t, 1, report.Duplicates)
+	assert.EqualValues(t, 2, report.Missing)
+	assert.Equal(t, 2, report.Keys)
+	assert.Equal(t, map[string][]int64{"a": {5, 6}}, report.MissingSequences)
+
+	assert.EqualValues(t, 6, ts.metricSum("cable_sequence_in_order", map[string]string{"channel": "EchoChannel"}))
+	assert.EqualValues(t, 1, ts.metricSum("cable_sequence_out_of_order", map[string]string{"channel": "EchoChannel"}))
+	assert.EqualValues(t, 1, ts.metricSum("cable_sequence_duplicates", map[string]string{"channel": "EchoChannel"}))
+
+	ts.run(t, `client.disconnect()`)
+
+	missing := ts.requireMetric(t, "cable_sequence_missing", map[string]string{"channel": "EchoChannel"})
+	assert.Equal(t, 2.0, missing[0].Value)
+}
+
+func TestSequenceMissingOnContextDone(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const client = cable.connect(CABLE_URL);
+		const channel = client.subscribe("EchoChannel", {}, { sequenceField: "seq" });
+		channel.ignoreReads();
+
+		channel.perform("echo", { seq: 1 });
+		channel.perform("echo", { seq: 4 });
+	`)
+
+	require.Eventually(t, func() bool {
+		return ts.run(t, `channel.sequenceReport().inOrder`).ToInteger() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The script doesn't disconnect explicitly: the connection is closed when the VU context is done
+	ts.CancelContext()
+
+	missing := ts.requireMetric(t, "cable_sequence_missing", map[string]string{"channel": "EchoChannel"})
+	assert.Equal(t, 2.0, missing[0].Value)
+
+	_, _ = ts.VU.Runtime().RunString(`client.disconnect()`)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, ts.metricSamples("cable_sequence_missing", nil), 1)
+}
+
+func TestSequenceTrackerStart(t *testing.T) {
+	start := int64(1)
+	tracker := newSequenceTracker("seq", "", &start)
+
+	assert.Equal(t, sequenceInOrder, tracker.track(map[string]interface{}{"seq": int8(3)}))
+	assert.Equal(t, sequenceOutOfOrder, tracker.track(map[string]interface{}{"seq": float64(1)}))
+	assert.Equal(t, sequenceDuplicate, tracker.track(map[string]interface{}{"seq": float64(1)}))
+	assert.Equal(t, sequenceUntracked, tracker.track(map[string]interface{}{"seq": "2"}))
+	assert.Equal(t, sequenceUntracked, tracker.track("3"))
+	assert.Equal(t, sequenceUntracked, tracker.track(map[string]interface{}{"seq": 4.5}))
+	assert.Equal(t, sequenceUntracked, tracker.track(map[string]interface{}{"seq": 1e19}))
+
+	report := tracker.summary()
+	assert.EqualValues(t, 1, report.Missing)
+	assert.Equal(t, map[string][]int64{"": {2}}, report.MissingSequences)
+}
+
+func TestSequenceTrackerMissingLimit(t *testing.T) {
+	tracker := newSequenceTracker("seq", "", nil)
+
+	for _, seq := range []int{1, 6001, 12001} {
+		assert.Equal(t, sequenceInOrder, tracker.track(map[string]interface{}{"seq": seq}))
+	}
+
+	assert.Len(t, tracker.missingOrder, maxRememberedMissing)
+	assert.Len(t, tracker.keys[""].missing, maxRememberedMissing)
+
+	// The oldest missing sequence numbers are forgotten
+	assert.Equal(t, sequenceDuplicate, tracker.track(map[string]interface{}{"seq": 2}))
+	assert.Equal(t, sequenceOutOfOrder, tracker.track(map[string]interface{}{"seq": 5000}))
+
+	report := tracker.summary()
+	assert.EqualValues(t, 11997, report.Missing)
+	assert.EqualValues(t, 2000, report.MissingSequences[""][0])
+}
+
+func TestSequenceOptionsValidation(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	_, err := ts.VU.Runtime().RunString(`
+		const client = cable.connect(CABLE_URL);
+		client.subscribe("EchoChannel", {}, { sequenceKeyField: "producer" });
+	`)
+	assert.ErrorContains(t, err, "sequenceKeyField and sequenceStart require sequenceField")
+
+	_, err = ts.VU.Runtime().RunString(`client.subscribe("EchoChannel").sequenceReport()`)
+	assert.ErrorContains(t, err, "sequence tracking is not enabled")
+}
@@ -0,0 +1,325 @@
+package cable
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+)
+
+// ConnectSSE connects to the AnyCable Server-Sent Events endpoint, creates and starts client, and returns it to the js.
+// The subscription is defined by the URL query params: either `identifier` (JSON-encoded identifier) or `channel`
+// (along with the params listed in the channelParams option).
+func (c *Cable) ConnectSSE(cableUrl string, opts sobek.Value) (*Client, error) {
+	state := c.vu.State()
+	if state == nil {
+		return nil, errCableInInitContext
+	}
+
+	cOpts, err := parseOptions(c.vu.Runtime(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if cOpts.codec() != JSONCodec {
+		return nil, fmt.Errorf("SSE transport only supports json codec")
+	}
+
+	identifier, err := sseIdentifier(cableUrl, cOpts.ChannelParams)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := cOpts.header()
+	setOrigin(headers, cableUrl)
+
+	logger := createLogger(state, cOpts)
+
+	tagsAndMeta := state.Tags.GetCurrentValues()
+	if state.Options.SystemTags.Has(metrics.TagURL) {
+		tagsAndMeta.SetSystemTagOrMetaIfEnabled(state.Options.SystemTags, metrics.TagURL, cableUrl)
+	}
+
+	performURL := cOpts.PerformURL
+	if performURL == "" {
+		// Stream query params (subscription, auth tokens) are not a part of commands
+		if performURL, err = stripQuery(cableUrl); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &sseTransport{
+		client:     &http.Client{Transport: state.Transport},
+		url:        cableUrl,
+		performURL: performURL,
+		header:     headers,
+		identifier: identifier,
+	}
+
+	if err := t.open(c.vu.Context(), cOpts.handshakeTimeout()); err != nil {
+		logger.Errorf("failed to connect: %v", err)
+		return nil, nil
+	}
+
+	client := c.newClient(t, cableUrl, headers, cOpts, logger, tagsAndMeta.Tags)
+
+	if identifier != "" {
+		// Subscription is performed via URL, so we must register the channel before receiving any messages
+		client.channels[identifier] = NewChannel(client, identifier)
+		client.implicitIdentifier = identifier
+	}
+
+	client.startLoops()
+
+	return client, nil
+}
+
+// sseIdentifier builds a channel identifier from the SSE URL query params;
+// other query params (e.g., auth tokens) are not included
+func sseIdentifier(cableUrl string, channelParams []string) (string, error) {
+	uri, err := url.Parse(cableUrl)
+	if err != nil {
+		return "", err
+	}
+
+	query := uri.Query()
+
+	if identifier := query.Get("identifier"); identifier != "" {
+		return identifier, nil
+	}
+
+	if query.Get("channel") == "" {
+		return "", nil
+	}
+
+	params := map[string]interface{}{"channel": query.Get("channel")}
+
+	for _, k := range channelParams {
+		if query.Has(k) {
+			params[k] = query.Get(k)
+		}
+	}
+
+	identifierJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	return string(identifierJSON), nil
+}
+
+func stripQuery(rawURL string) (string, error) {
+	uri, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	uri.RawQuery = ""
+	uri.Fragment = ""
+
+	return uri.String(), nil
+}
+
+// sseTransport receives messages from the Server-Sent Events stream
+// and sends commands via HTTP POST requests
+type sseTransport struct {
+	client     *http.Client
+	url        string
+	performURL string
+	header     http.Header
+	identifier string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	body   io.ReadCloser
+	reader *bufio.Reader
+}
+
+var _ transport = (*sseTransport)(nil)
+
+func (t *sseTransport) open(ctx context.Context, timeout time.Duration) error {
+	t.ctx, t.cancel = context.WithCancel(ctx)
+
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header = t.header.Clone()
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	// We cannot use http.Client.Timeout, since it limits the lifetime of the whole stream
+	timer := time.AfterFunc(timeout, t.cancel)
+	resp, err := t.client.Do(req)
+	timer.Stop()
+
+	if err != nil {
+		t.cancel()
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		t.cancel()
+		return fmt.Errorf("unexpected response status: %d", resp.StatusCode)
+	}
+
+	t.body = resp.Body
+	t.reader = bufio.NewReader(resp.Body)
+
+	return nil
+}
+
+// Receive reads the next event from the stream.
+// Events could contain either Action Cable protocol messages or raw broadcasted data
+// (in the latter case, the message is attributed to the URL subscription).
+func (t *sseTransport) Receive(msg *cableMsg) (int, error) {
+	for {
+		event, data, err := t.readEvent()
+		if err != nil {
+			return 0, err
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		if isProtocolEvent(data) {
+			if err := json.Unmarshal(data, msg); err != nil {
+				return len(data), &decodeError{err}
+			}
+		} else {
+			var payload interface{}
+			if err := json.Unmarshal(data, &payload); err != nil {
+				payload = string(data)
+			}
+			msg.Message = payload
+		}
+
+		if msg.Type == "" && event != "" && event != "message" {
+			msg.Type = event
+		}
+
+		if msg.Identifier == "" && msg.Type != "welcome" && msg.Type != "ping" && msg.Type != "disconnect" {
+			msg.Identifier = t.identifier
+		}
+
+		return len(data), nil
+	}
+}
+
+// sseProtocolTypes contains the types of the Action Cable protocol messages
+var sseProtocolTypes = map[string]bool{
+	"welcome":              true,
+	"ping":                 true,
+	"confirm_subscription": true,
+	"reject_subscription":  true,
+	"disconnect":           true,
+}
+
+// isProtocolEvent returns true if the event data is an Action Cable protocol message:
+// either a message of a known type or a broadcast with both identifier and message.
+// Other payloads (even with the "type" field) are treated as raw broadcasted data.
+func isProtocolEvent(data []byte) bool {
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(data, &probe) != nil {
+		return false
+	}
+
+	var msgType string
+	if json.Unmarshal(probe["type"], &msgType) == nil && sseProtocolTypes[msgType] {
+		return true
+	}
+
+	return probe["identifier"] != nil && probe["message"] != nil
+}
+
+// readEvent reads lines until the end of the event (an empty line) and returns the event name and data
+func (t *sseTransport) readEvent() (string, []byte, error) {
+	var event string
+	var data bytes.Buffer
+
+	for {
+		line, err := t.reader.ReadString('\n')
+		if err != nil {
+			return "", nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if data.Len() > 0 || event != "" {
+				return event, data.Bytes(), nil
+			}
+			continue
+		}
+
+		// Comments (used as heartbeats)
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+}
+
+// Send performs an HTTP POST request with the JSON-encoded command
+func (t *sseTransport) Send(msg *cableMsg) (int, error) {
+	// Subscription is performed via URL
+	if msg.Command == "subscribe" && msg.Identifier == t.identifier {
+		return 0, nil
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.performURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header = t.header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return len(body), err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return len(body), fmt.Errorf("%s command failed with status: %d", msg.Command, resp.StatusCode)
+	}
+
+	return len(body), nil
+}
+
+func (t *sseTransport) Close() error {
+	t.cancel()
+	return t.body.Close()
+}
@@ -0,0 +1,234 @@
+package cable
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseServer is a minimal AnyCable SSE stand-in: the GET request opens the stream subscribed via URL params,
+// POST requests are commands; performed actions data is broadcasted to the stream.
+type sseServer struct {
+	*httptest.Server
+
+	events chan string
+
+	mu       sync.Mutex
+	streams  []string
+	commands []sseCommand
+}
+
+type sseCommand struct {
+	URL string
+	Msg cableMsg
+}
+
+func startSSEServer(t *testing.T) *sseServer {
+	t.Helper()
+
+	s := &sseServer{events: make(chan string, 16)}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			s.stream(w, r)
+		case http.MethodPost:
+			s.command(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+
+	t.Cleanup(func() {
+		// Streams are only finished when clients disconnect
+		s.CloseClientConnections()
+		s.Close()
+	})
+
+	return s
+}
+
+func (s *sseServer) stream(w http.ResponseWriter, r *http.Request) {
+	flusher := w.(http.Flusher)
+
+	s.mu.Lock()
+	s.streams = append(s.streams, r.URL.RawQuery)
+	s.mu.Unlock()
+
+	identifier := r.URL.Query().Get("identifier")
+	if identifier == "" {
+		identifier = fmt.Sprintf(`{"channel":%q,"room_id":%q}`, r.URL.Query().Get("channel"), r.URL.Query().Get("room_id"))
+	}
+
+	confirm, _ := json.Marshal(map[string]string{"type": "confirm_subscription", "identifier": identifier})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "data: {\"type\":\"welcome\"}\n\n")
+	fmt.Fprintf(w, ": heartbeat\n\n")
+	fmt.Fprintf(w, "data: %s\n\n", confirm)
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-s.events:
+			fmt.Fprint(w, event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *sseServer) command(w http.ResponseWriter, r *http.Request) {
+	var msg cableMsg
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.commands = append(s.commands, sseCommand{URL: r.URL.String(), Msg: msg})
+	s.mu.Unlock()
+
+	if msg.Command == "message" {
+		// Raw broadcasted data preceded by an unrelated message (to check matchers)
+		s.events <- fmt.Sprintf("data: {\"action\":\"ignored\"}\n\ndata: %s\n\n", msg.Data)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *sseServer) recordedCommands() []sseCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]sseCommand(nil), s.commands...)
+}
+
+func TestSSE(t *testing.T) {
+	ts := newTestState(t)
+	server := startSSEServer(t)
+
+	require.NoError(t, ts.VU.Runtime().Set("SSE_URL", server.URL+"/events?channel=ChatChannel&room_id=42&token=secret"))
+
+	val := ts.run(t, `
+		const client = cable.connectSSE(SSE_URL, { channelParams: ["room_id"] });
+		const channel = client.subscribe("ChatChannel", { room_id: "42" });
+
+		channel.perform("speak", { text: "hello" });
+
+		const msg = channel.receive({ action: "speak" });
+		client.disconnect();
+
+		msg.text
+	`)
+
+	assert.Equal(t, "hello", val.String())
+
+	server.mu.Lock()
+	assert.Equal(t, []string{"channel=ChatChannel&room_id=42&token=secret"}, server.streams)
+	server.mu.Unlock()
+
+	// Subscription is performed via URL
+	commands := server.recordedCommands()
+	require.Len(t, commands, 1)
+
+	assert.Equal(t, "/events", commands[0].URL)
+	assert.Equal(t, "message", commands[0].Msg.Command)
+	assert.Equal(t, `{"channel":"ChatChannel","room_id":"42"}`, commands[0].Msg.Identifier)
+	assert.JSONEq(t, `{"action":"speak","text":"hello"}`, commands[0].Msg.Data)
+}
+
+func TestSSEPerformURL(t *testing.T) {
+	ts := newTestState(t)
+	server := startSSEServer(t)
+
+	require.NoError(t, ts.VU.Runtime().Set("SSE_URL", server.URL+`/events?identifier={"channel":"ChatChannel","room_id":"1"}`))
+	require.NoError(t, ts.VU.Runtime().Set("PERFORM_URL", server.URL+"/perform"))
+
+	ts.run(t, `
+		const client = cable.connectSSE(SSE_URL, { performUrl: PERFORM_URL });
+		const channel = client.subscribe("ChatChannel", { room_id: "1" });
+
+		// Extra subscriptions are sent as commands
+		client.subscribeAsync("NotificationsChannel");
+
+		channel.perform("speak", { text: "hello" });
+		channel.receive({ text: "hello" });
+	`)
+
+	commands := server.recordedCommands()
+	require.Len(t, commands, 2)
+
+	assert.Equal(t, "/perform", commands[0].URL)
+	assert.Equal(t, "subscribe", commands[0].Msg.Command)
+	assert.Equal(t, `{"channel":"NotificationsChannel"}`, commands[0].Msg.Identifier)
+
+	assert.Equal(t, "message", commands[1].Msg.Command)
+}
+
+func TestSSEIdentifier(t *testing.T) {
+	identifier, err := sseIdentifier("http://localhost/events?channel=ChatChannel&room_id=42&token=secret", []string{"room_id", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"channel":"ChatChannel","room_id":"42"}`, identifier)
+
+	identifier, err = sseIdentifier("http://localhost/events?token=secret", nil)
+	require.NoError(t, err)
+	assert.Empty(t, identifier)
+}
+
+func TestSSEOnlyJSON(t *testing.T) {
+	ts := newTestState(t)
+
+	_, err := ts.VU.Runtime().RunString(`cable.connectSSE("http://localhost/events", { codec: "msgpack" })`)
+	assert.ErrorContains(t, err, "SSE transport only supports json codec")
+}
+
+func TestSSEReceive(t *testing.T) {
+	stream := `data: {"type":"chat","text":"hi"}
+
+data: {"identifier":"{\"channel\":\"NotificationsChannel\"}","message":{"text":"hey"}}
+
+data: {"type":"ping","message":1}
+
+data: {"type":"confirm_subscription","identifier":42}
+
+`
+	transport := &sseTransport{identifier: `{"channel":"ChatChannel"}`, reader: bufio.NewReader(strings.NewReader(stream))}
+
+	// Raw payloads are kept as is even if they have the type field
+	var msg cableMsg
+	_, err := transport.Receive(&msg)
+	require.NoError(t, err)
+	assert.Equal(t, "", msg.Type)
+	assert.Equal(t, `{"channel":"ChatChannel"}`, msg.Identifier)
+	assert.Equal(t, map[string]interface{}{"type": "chat", "text": "hi"}, msg.Message)
+
+	msg = cableMsg{}
+	_, err = transport.Receive(&msg)
+	require.NoError(t, err)
+	assert.Equal(t, `{"channel":"NotificationsChannel"}`, msg.Identifier)
+	assert.Equal(t, map[string]interface{}{"text": "hey"}, msg.Message)
+
+	msg = cableMsg{}
+	_, err = transport.Receive(&msg)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", msg.Type)
+	assert.Empty(t, msg.Identifier)
+
+	// Malformed protocol messages are reported as decode errors (to be skipped if skipInvalidFrames is set)
+	_, err = transport.Receive(&cableMsg{})
+
+	var decodeErr *decodeError
+	assert.ErrorAs(t, err, &decodeErr)
+}
@@ -0,0 +1,65 @@
+package cable
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/sobek"
+)
+
+// subscribeOptions contains the options passed as the third argument of subscribe
+type subscribeOptions struct {
+	// Schema is the JSON Schema object (or a path to the schema file) to validate incoming messages against
+	Schema json.RawMessage `json:"schema"`
+	// SchemaLogViolations is the number of first invalid messages to log
+	SchemaLogViolations int `json:"schemaLogViolations"`
+	// SequenceField is the (dotted) path to the message sequence number
+	SequenceField string `json:"sequenceField"`
+	// SequenceKeyField is the path to the producer key (sequences are tracked independently for each key)
+	SequenceKeyField string `json:"sequenceKeyField"`
+	// SequenceStart is the first expected sequence number (by default, the first received one)
+	SequenceStart *int64 `json:"sequenceStart"`
+	// Tracker is the name of the delivery tracker to acknowledge received messages in
+	Tracker string `json:"tracker"`
+	// TrackerIDField is the (dotted) path to the tracked message ID (defaults to "tracking_id")
+	TrackerIDField string `json:"trackerIdField"`
+}
+
+func parseSubscribeOptions(rt *sobek.Runtime, in sobek.Value) (*subscribeOptions, error) {
+	var opts subscribeOptions
+	if err := decodeOptions(rt, in, &opts); err != nil {
+		return nil, err
+	}
+
+	return &opts, nil
+}
+
+// configure applies the subscribe options to the channel
+func (ch *Channel) configure(opts *subscribeOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	if len(opts.Schema) > 0 && string(opts.Schema) != "null" {
+		schema, err := newChannelSchema(opts.Schema, opts.SchemaLogViolations, ch.client.scriptDir)
+		if err != nil {
+			return err
+		}
+
+		ch.schema = schema
+	}
+
+	if opts.SequenceField != "" {
+		ch.sequence = newSequenceTracker(opts.SequenceField, opts.SequenceKeyField, opts.SequenceStart)
+	} else if opts.SequenceKeyField != "" || opts.SequenceStart != nil {
+		return fmt.Errorf("sequenceKeyField and sequenceStart require sequenceField")
+	}
+
+	if opts.Tracker != "" {
+		ch.delivery = ch.client.trackers.get(opts.Tracker).addReceiver(opts.TrackerIDField)
+	} else if opts.TrackerIDField != "" {
+		return fmt.Errorf("trackerIdField requires tracker")
+	}
+
+	return nil
+}
@@ -0,0 +1,523 @@
+package cable
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	defaultTrackerIDField = "tracking_id"
+
+	// maxEarlyDeliveries is the max number of acknowledgements kept for not yet registered messages
+	// (the oldest ones are dropped first)
+	maxEarlyDeliveries = 10000
+
+	// maxReportedDeliveries is the max number of missing messages included into the report
+	maxReportedDeliveries = 100
+
+	// maxTrackedMessages is the max number of messages kept by the tracker; the oldest messages are evicted
+	// (their stats are kept, missing deliveries are reported, and late acknowledgements are ignored)
+	maxTrackedMessages = 100000
+
+	// maxTrackedLatencies is the size of the latencies sample used to calculate percentiles
+	maxTrackedLatencies = 10000
+)
+
+// trackerRegistry contains delivery trackers shared by all the VUs of the process
+type trackerRegistry struct {
+	mu       sync.Mutex
+	trackers map[string]*deliveryTracker
+}
+
+func newTrackerRegistry() *trackerRegistry {
+	return &trackerRegistry{trackers: make(map[string]*deliveryTracker)}
+}
+
+func (r *trackerRegistry) get(name string) *deliveryTracker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.trackers[name]
+	if !ok {
+		t = &deliveryTracker{
+			name:     name,
+			messages: make(map[string]*trackedMessage),
+			early:    make(map[string]map[*deliveryReceiver]time.Time),
+		}
+		r.trackers[name] = t
+	}
+
+	return t
+}
+
+// deliveryTracker matches messages registered by publishers with the ones received by subscribers
+type deliveryTracker struct {
+	name string
+
+	mu sync.Mutex
+	// receivers is the number of active receivers
+	receivers int
+	// registered is the number of registered messages (used as the registration sequence number)
+	registered int
+	messages   map[string]*trackedMessage
+	// order contains message IDs in the registration order
+	order []string
+	// evicted contains the stats of the messages evicted from the tracker
+	evicted struct{ expected, delivered int }
+	// early contains acknowledgements received before the message has been registered (by receivers)
+	early map[string]map[*deliveryReceiver]time.Time
+	// earlyOrder contains early acknowledgements in the order of arrival (entries could be stale)
+	earlyOrder []earlyDelivery
+	// latencies contains a uniform sample of delivery latencies in milliseconds
+	latencies    []float64
+	latencyCount int
+	rng          *rand.Rand
+}
+
+type trackedMessage struct {
+	// seq is the registration sequence number
+	seq    int
+	sentAt time.Time
+	// expected is the number of receivers active at the time of registration
+	expected  int
+	delivered map[*deliveryReceiver]bool
+	// reported is the number of missing deliveries already reported
+	reported int
+}
+
+type earlyDelivery struct {
+	id       string
+	receiver *deliveryReceiver
+}
+
+// deliveryReceiver is the subscriber channel acknowledging tracked messages.
+// The receiver expects messages registered while it's active (i.e., with seq in the (joined, left] range).
+type deliveryReceiver struct {
+	tracker *deliveryTracker
+	idField string
+	joined  int
+	// left is the registration sequence number at the time of release (-1 while active)
+	left int
+}
+
+func (t *deliveryTracker) addReceiver(idField string) *deliveryReceiver {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.receivers++
+
+	if idField == "" {
+		idField = defaultTrackerIDField
+	}
+
+	return &deliveryReceiver{tracker: t, idField: idField, joined: t.registered, left: -1}
+}
+
+// release stops the receiver from expecting newly registered messages (e.g., when the connection is closed)
+// and returns the number of the expected messages the receiver has missed (not reported before)
+func (r *deliveryReceiver) release() int {
+	t := r.tracker
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r.left >= 0 {
+		return 0
+	}
+
+	r.left = t.registered
+	t.receivers--
+
+	// Messages registered while the receiver was active are at the tail of the order
+	from := r.joined - (t.registered - len(t.order))
+	if from < 0 {
+		from = 0
+	}
+
+	missed := 0
+
+	for _, id := range t.order[from:] {
+		msg := t.messages[id]
+
+		if msg.delivered[r] || msg.reported >= msg.expected-len(msg.delivered) {
+			continue
+		}
+
+		msg.reported++
+		missed++
+	}
+
+	return missed
+}
+
+func (r *deliveryReceiver) expects(msg *trackedMessage) bool {
+	return msg.seq > r.joined && (r.left < 0 || msg.seq <= r.left)
+}
+
+// register adds the message sent at the specified time and returns latencies of the deliveries acknowledged before the registration
+// and the number of missing deliveries of the evicted messages (not reported before)
+func (t *deliveryTracker) register(id string, sentAt time.Time) ([]time.Duration, int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.messages[id]; ok {
+		return nil, 0, fmt.Errorf("message %s is already registered in the %s tracker", id, t.name)
+	}
+
+	t.registered++
+
+	msg := &trackedMessage{seq: t.registered, sentAt: sentAt, expected: t.receivers, delivered: make(map[*deliveryReceiver]bool)}
+	t.messages[id] = msg
+	t.order = append(t.order, id)
+
+	missed := 0
+	if len(t.order) > maxTrackedMessages {
+		missed = t.evict()
+	}
+
+	var latencies []time.Duration
+
+	for receiver, at := range t.early[id] {
+		if latency, ok := t.deliver(msg, receiver, at); ok {
+			latencies = append(latencies, latency)
+		}
+	}
+
+	delete(t.early, id)
+
+	return latencies, missed, nil
+}
+
+// evict removes the oldest message keeping its stats and returns the number of its missing deliveries not reported before
+func (t *deliveryTracker) evict() int {
+	id := t.order[0]
+	msg := t.messages[id]
+
+	t.order = t.order[1:]
+	delete(t.messages, id)
+
+	missing := msg.expected - len(msg.delivered)
+
+	t.evicted.expected += msg.expected
+	t.evicted.delivered += len(msg.delivered)
+
+	return missing - msg.reported
+}
+
+// ack marks the message as delivered to the receiver; returns false if the message is not expected
+func (t *deliveryTracker) ack(receiver *deliveryReceiver, id string, at time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	msg, ok := t.messages[id]
+	if !ok {
+		t.addEarly(id, receiver, at)
+
+		return 0, false
+	}
+
+	return t.deliver(msg, receiver, at)
+}
+
+// addEarly keeps the acknowledgement until the message is registered dropping the oldest ones if the limit is reached
+// (e.g., acknowledgements of messages which are never registered)
+func (t *deliveryTracker) addEarly(id string, receiver *deliveryReceiver, at time.Time) {
+	for len(t.earlyOrder) >= maxEarlyDeliveries {
+		oldest := t.earlyOrder[0]
+		t.earlyOrder = t.earlyOrder[1:]
+
+		if acks := t.early[oldest.id]; acks != nil {
+			delete(acks, oldest.receiver)
+
+			if len(acks) == 0 {
+				delete(t.early, oldest.id)
+			}
+		}
+	}
+
+	if t.early[id] == nil {
+		t.early[id] = make(map[*deliveryReceiver]time.Time)
+	}
+
+	t.early[id][receiver] = at
+	t.earlyOrder = append(t.earlyOrder, earlyDelivery{id, receiver})
+}
+
+func (t *deliveryTracker) deliver(msg *trackedMessage, receiver *deliveryReceiver, at time.Time) (time.Duration, bool) {
+	// Messages not received before the release are reported as missing
+	if receiver.left >= 0 || !receiver.expects(msg) || msg.delivered[receiver] {
+		return 0, false
+	}
+
+	msg.delivered[receiver] = true
+
+	latency := at.Sub(msg.sentAt)
+	if latency < 0 {
+		latency = 0
+	}
+
+	t.sampleLatency(metrics.D(latency))
+
+	return latency, true
+}
+
+// sampleLatency adds the latency to the sample (using reservoir sampling once the sample is full)
+func (t *deliveryTracker) sampleLatency(latency float64) {
+	t.latencyCount++
+
+	if len(t.latencies) < maxTrackedLatencies {
+		t.latencies = append(t.latencies, latency)
+		return
+	}
+
+	if t.rng == nil {
+		t.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	if i := t.rng.Intn(t.latencyCount); i < maxTrackedLatencies {
+		t.latencies[i] = latency
+	}
+}
+
+// DeliveryReport contains delivery stats of the tracker
+type DeliveryReport struct {
+	Name     string `js:"name"`
+	Messages int    `js:"messages"`
+	// Receivers is the number of currently subscribed receivers
+	Receivers int `js:"receivers"`
+	// Expected is the number of expected deliveries (messages multiplied by receivers subscribed at the time of registration)
+	Expected  int     `js:"expected"`
+	Delivered int     `js:"delivered"`
+	Ratio     float64 `js:"ratio"`
+	// Latency contains delivery latency percentiles in milliseconds
+	Latency map[string]float64 `js:"latency"`
+	// Missing contains the first messages not delivered to all the receivers
+	Missing []*MissingDelivery `js:"missing"`
+}
+
+// MissingDelivery describes the message not delivered to some receivers
+type MissingDelivery struct {
+	ID string `js:"id"`
+	// Receivers is the number of receivers which haven't received the message
+	Receivers int `js:"receivers"`
+}
+
+// report returns the delivery stats and the number of missing deliveries not reported before
+func (t *deliveryTracker) report() (*DeliveryReport, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := &DeliveryReport{
+		Name:      t.name,
+		Messages:  t.registered,
+		Receivers: t.receivers,
+		Expected:  t.evicted.expected,
+		Delivered: t.evicted.delivered,
+		Latency:   latencyPercentiles(t.latencies),
+	}
+
+	unreported := 0
+
+	for _, id := range t.order {
+		msg := t.messages[id]
+
+		report.Expected += msg.expected
+		report.Delivered += len(msg.delivered)
+
+		missing := msg.expected - len(msg.delivered)
+		if missing == 0 {
+			continue
+		}
+
+		if missing > msg.reported {
+			unreported += missing - msg.reported
+			msg.reported = missing
+		}
+
+		if len(report.Missing) < maxReportedDeliveries {
+			report.Missing = append(report.Missing, &MissingDelivery{ID: id, Receivers: missing})
+		}
+	}
+
+	if report.Expected > 0 {
+		report.Ratio = float64(report.Delivered) / float64(report.Expected)
+	}
+
+	return report, unreported
+}
+
+func latencyPercentiles(latencies []float64) map[string]float64 {
+	result := make(map[string]float64)
+
+	if len(latencies) == 0 {
+		return result
+	}
+
+	sorted := append([]float64(nil), latencies...)
+	sort.Float64s(sorted)
+
+	percentile := func(p float64) float64 {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+
+	result["min"] = sorted[0]
+	result["med"] = percentile(0.5)
+	result["p90"] = percentile(0.9)
+	result["p95"] = percentile(0.95)
+	result["p99"] = percentile(0.99)
+	result["max"] = sorted[len(sorted)-1]
+
+	return result
+}
+
+// Tracker is the JS interface to the delivery tracker shared by all the VUs
+type Tracker struct {
+	cable   *Cable
+	tracker *deliveryTracker
+}
+
+// Tracker returns the process-wide delivery tracker with the specified name
+func (c *Cable) Tracker(name string) (*Tracker, error) {
+	if name == "" {
+		return nil, fmt.Errorf("tracker name is required")
+	}
+
+	return &Tracker{cable: c, tracker: c.root.trackers.get(name)}, nil
+}
+
+// Register registers the message (with the current time as the send time) and returns its ID.
+// A random ID is generated if none provided.
+func (t *Tracker) Register(id string) (string, error) {
+	if t.cable.vu.State() == nil {
+		return "", errCableInInitContext
+	}
+
+	if id == "" {
+		var err error
+		if id, err = randomUUID(); err != nil {
+			return "", err
+		}
+	}
+
+	latencies, missed, err := t.tracker.register(id, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	// Acknowledgements received before the registration are tracked on behalf of the publisher
+	for _, latency := range latencies {
+		t.push(t.cable.metrics.DeliveryLatency, metrics.D(latency))
+		t.push(t.cable.metrics.DeliveryRatio, 1)
+	}
+
+	// Missing deliveries of the evicted messages are tracked on behalf of the publisher, too
+	for i := 0; i < missed; i++ {
+		t.push(t.cable.metrics.DeliveryRatio, 0)
+	}
+
+	return id, nil
+}
+
+// Report returns the delivery stats; missing deliveries not reported yet (on receivers release or messages eviction)
+// are tracked by the cable_delivery_ratio metric (once).
+func (t *Tracker) Report() (*DeliveryReport, error) {
+	if t.cable.vu.State() == nil {
+		return nil, errCableInInitContext
+	}
+
+	report, unreported := t.tracker.report()
+
+	for i := 0; i < unreported; i++ {
+		t.push(t.cable.metrics.DeliveryRatio, 0)
+	}
+
+	return report, nil
+}
+
+func (t *Tracker) push(metric *metrics.Metric, value float64) {
+	state := t.cable.vu.State()
+
+	metrics.PushIfNotDone(t.cable.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   state.Tags.GetCurrentValues().Tags.With("tracker", t.tracker.name),
+		},
+		Time:  time.Now(),
+		Value: value,
+	})
+}
+
+// trackDelivery acknowledges the tracked message and emits delivery metrics
+func (ch *Channel) trackDelivery(msg *cableMsg) {
+	val, ok := lookupPath(msg.Message, ch.delivery.idField)
+	if !ok {
+		return
+	}
+
+	latency, ok := ch.delivery.tracker.ack(ch.delivery, fmt.Sprintf("%v", val), msg.receivedAt)
+	if !ok {
+		return
+	}
+
+	c := ch.client
+
+	tags := c.sampleTags.With("tracker", ch.delivery.tracker.name)
+	if name := c.channelName(ch.identifier); name != "" {
+		tags = tags.With("channel", name)
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.samplesOutput, metrics.ConnectedSamples{
+		Samples: []metrics.Sample{
+			{
+				TimeSeries: metrics.TimeSeries{Metric: c.metrics.DeliveryLatency, Tags: tags},
+				Time:       msg.receivedAt,
+				Value:      metrics.D(latency),
+			},
+			{
+				TimeSeries: metrics.TimeSeries{Metric: c.metrics.DeliveryRatio, Tags: tags},
+				Time:       msg.receivedAt,
+				Value:      1,
+			},
+		},
+		Tags: tags,
+		Time: msg.receivedAt,
+	})
+}
+
+// releaseDelivery stops expecting tracked messages (when the connection is closed)
+// and tracks the messages which haven't been received as missing
+func (ch *Channel) releaseDelivery() {
+	if ch.delivery == nil {
+		return
+	}
+
+	missed := ch.delivery.release()
+	if missed == 0 {
+		return
+	}
+
+	c := ch.client
+
+	tags := c.sampleTags.With("tracker", ch.delivery.tracker.name)
+	if name := c.channelName(ch.identifier); name != "" {
+		tags = tags.With("channel", name)
+	}
+
+	now := time.Now()
+	samples := make(metrics.Samples, missed)
+
+	for i := range samples {
+		samples[i] = metrics.Sample{
+			TimeSeries: metrics.TimeSeries{Metric: c.metrics.DeliveryRatio, Tags: tags},
+			Time:       now,
+			Value:      0,
+		}
+	}
+
+	c.pushOnClose(samples)
+}
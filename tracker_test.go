@@ -0,0 +1,249 @@
+package cable
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/metrics"
+)
+
+func TestDeliveryTracker(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	ts.run(t, `
+		const tracker = cable.tracker("echo");
+
+		const publisher = cable.connect(CABLE_URL);
+		const pubChannel = publisher.subscribe("EchoChannel", {}, { tracker: "echo" });
+		pubChannel.ignoreReads();
+
+		const subscriber = cable.connect(CABLE_URL);
+		const subChannel = subscriber.subscribe("EchoChannel", {}, { tracker: "echo", trackerIdField: "meta.id" });
+		subChannel.ignoreReads();
+
+		const id = tracker.register();
+		pubChannel.perform("broadcast", { tracking_id: id, meta: { id } });
+
+		tracker.register("lost");
+	`)
+
+	var report *DeliveryReport
+
+	require.Eventually(t, func() bool {
+		report = ts.run(t, `tracker.report()`).Export().(*DeliveryReport)
+		return report.Delivered == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "echo", report.Name)
+	assert.Equal(t, 2, report.Messages)
+	assert.Equal(t, 2, report.Receivers)
+	assert.Equal(t, 4, report.Expected)
+	assert.Equal(t, 0.5, report.Ratio)
+	assert.Contains(t, report.Latency, "p95")
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, "lost", report.Missing[0].ID)
+	assert.Equal(t, 2, report.Missing[0].Receivers)
+
+	latency := ts.requireMetric(t, "cable_delivery_latency", map[string]string{"tracker": "echo", "channel": "EchoChannel"})
+	assert.Len(t, latency, 2)
+
+	delivered := ts.requireMetric(t, "cable_delivery_ratio", map[string]string{"tracker": "echo", "channel": "EchoChannel"})
+	assert.Len(t, delivered, 2)
+
+	// Missing deliveries are reported once
+	ts.run(t, `tracker.report()`)
+
+	var missing int
+	for _, s := range ts.requireMetric(t, "cable_delivery_ratio", map[string]string{"tracker": "echo"}) {
+		if s.Value == 0 {
+			missing++
+		}
+	}
+	assert.Equal(t, 2, missing)
+}
+
+func TestDeliveryTrackerEarlyAck(t *testing.T) {
+	tracker := newTrackerRegistry().get("early")
+	receiver := tracker.addReceiver("")
+
+	assert.Equal(t, defaultTrackerIDField, receiver.idField)
+
+	sentAt := time.Now()
+
+	_, ok := tracker.ack(receiver, "1", sentAt.Add(5*time.Millisecond))
+	assert.False(t, ok)
+
+	latencies, _, err := tracker.register("1", sentAt)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{5 * time.Millisecond}, latencies)
+
+	// Duplicates are ignored
+	_, ok = tracker.ack(receiver, "1", time.Now())
+	assert.False(t, ok)
+
+	_, _, err = tracker.register("1", sentAt)
+	assert.ErrorContains(t, err, "already registered")
+
+	// Receivers subscribed after the registration don't expect the message
+	late := tracker.addReceiver("id")
+	_, ok = tracker.ack(late, "1", time.Now())
+	assert.False(t, ok)
+
+	report, unreported := tracker.report()
+	assert.Equal(t, 1.0, report.Ratio)
+	assert.Equal(t, 0, unreported)
+
+	// The oldest acknowledgements of never registered messages are dropped
+	for i := 0; i <= maxEarlyDeliveries; i++ {
+		tracker.ack(receiver, "unknown-"+strconv.Itoa(i), sentAt)
+	}
+
+	assert.Len(t, tracker.early, maxEarlyDeliveries)
+	assert.NotContains(t, tracker.early, "unknown-0")
+
+	latencies, _, err = tracker.register("unknown-0", sentAt)
+	require.NoError(t, err)
+	assert.Empty(t, latencies)
+
+	latencies, _, err = tracker.register("unknown-"+strconv.Itoa(maxEarlyDeliveries), sentAt)
+	require.NoError(t, err)
+	assert.Len(t, latencies, 1)
+}
+
+func TestDeliveryTrackerRelease(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	// Subscribers reconnecting on each iteration don't inflate the number of expected deliveries
+	ts.run(t, `
+		const tracker = cable.tracker("release");
+
+		for (let i = 0; i < 3; i++) {
+			const client = cable.connect(CABLE_URL);
+			client.subscribe("EchoChannel", {}, { tracker: "release" }).ignoreReads();
+			client.disconnect();
+		}
+
+		const subscriber = cable.connect(CABLE_URL);
+		const channel = subscriber.subscribe("EchoChannel", {}, { tracker: "release" });
+		channel.ignoreReads();
+
+		const id = tracker.register();
+		channel.perform("broadcast", { tracking_id: id });
+	`)
+
+	var report *DeliveryReport
+
+	require.Eventually(t, func() bool {
+		report = ts.run(t, `tracker.report()`).Export().(*DeliveryReport)
+		return report.Delivered == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 1, report.Receivers)
+	assert.Equal(t, 1, report.Expected)
+	assert.Equal(t, 1.0, report.Ratio)
+
+	delivered := ts.requireMetric(t, "cable_delivery_ratio", map[string]string{"tracker": "release"})
+	assert.Len(t, delivered, 1)
+
+	// Receivers are released when the VU context is done, too;
+	// messages which haven't been received by then are tracked as missing (without calling report())
+	ts.run(t, `tracker.register("lost")`)
+	ts.CancelContext()
+
+	var missing []metrics.Sample
+
+	require.Eventually(t, func() bool {
+		missing = nil
+		for _, s := range ts.metricSamples("cable_delivery_ratio", map[string]string{"tracker": "release", "channel": "EchoChannel"}) {
+			if s.Value == 0 {
+				missing = append(missing, s)
+			}
+		}
+		return len(missing) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Len(t, missing, 1)
+
+	report, unreported := ts.module.root.trackers.get("release").report()
+	assert.Equal(t, 0, report.Receivers)
+	assert.Equal(t, 0, unreported)
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, "lost", report.Missing[0].ID)
+}
+
+func TestDeliveryTrackerLimits(t *testing.T) {
+	tracker := newTrackerRegistry().get("limits")
+	receiver := tracker.addReceiver("")
+
+	sentAt := time.Now()
+
+	evictedMissed := 0
+
+	for i := 0; i < maxTrackedMessages+10; i++ {
+		id := strconv.Itoa(i)
+
+		_, missed, err := tracker.register(id, sentAt)
+		require.NoError(t, err)
+
+		// Missing deliveries of the evicted messages are reported right away
+		evictedMissed += missed
+
+		// The first messages are lost
+		if i >= 5 {
+			_, ok := tracker.ack(receiver, id, sentAt)
+			require.True(t, ok)
+		}
+	}
+
+	assert.Len(t, tracker.messages, maxTrackedMessages)
+	assert.Len(t, tracker.latencies, maxTrackedLatencies)
+
+	// Stats of the evicted messages are kept
+	report, unreported := tracker.report()
+	assert.Equal(t, maxTrackedMessages+10, report.Messages)
+	assert.Equal(t, maxTrackedMessages+10, report.Expected)
+	assert.Equal(t, maxTrackedMessages+5, report.Delivered)
+	assert.Equal(t, 5, evictedMissed)
+	assert.Equal(t, 0, unreported)
+	assert.Empty(t, report.Missing)
+
+	_, _, err := tracker.register("not-received", sentAt)
+	require.NoError(t, err)
+
+	// Messages not received by the time of release are reported once
+	assert.Equal(t, 1, receiver.release())
+	assert.Equal(t, 0, receiver.release())
+
+	_, ok := tracker.ack(receiver, "not-received", sentAt)
+	assert.False(t, ok)
+
+	_, _, err = tracker.register("after-release", sentAt)
+	require.NoError(t, err)
+
+	_, ok = tracker.ack(receiver, "after-release", sentAt)
+	assert.False(t, ok)
+
+	report, unreported = tracker.report()
+	assert.Equal(t, 0, report.Receivers)
+	assert.Equal(t, maxTrackedMessages+11, report.Expected)
+	assert.Equal(t, 0, unreported)
+}
+
+func TestDeliveryTrackerOptionsValidation(t *testing.T) {
+	ts := newTestState(t)
+	ts.startMockServer(t, echoServerConfig())
+
+	_, err := ts.VU.Runtime().RunString(`
+		const client = cable.connect(CABLE_URL);
+		client.subscribe("EchoChannel", {}, { trackerIdField: "id" });
+	`)
+	assert.ErrorContains(t, err, "trackerIdField requires tracker")
+
+	_, err = ts.VU.Runtime().RunString(`cable.tracker("")`)
+	assert.ErrorContains(t, err, "tracker name is required")
+}
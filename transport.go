@@ -0,0 +1,97 @@
+package cable
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// transport is used by Client to exchange Action Cable messages with a server.
+// Both Receive and Send return the size of the message payload in bytes.
+type transport interface {
+	Receive(msg *cableMsg) (int, error)
+	Send(msg *cableMsg) (int, error)
+	Close() error
+}
+
+// rawTransport is implemented by transports supporting raw frames
+type rawTransport interface {
+	// SendRaw writes the data as is (bypassing the codec)
+	SendRaw(data []byte, binary bool) (int, error)
+	// setTap sets the tap to capture incoming raw frames
+	setTap(tap *rawTap)
+}
+
+// decodeError is returned by transports when a frame has been received but couldn't be decoded
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return fmt.Sprintf("failed to decode message: %v", e.err)
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// wsTransport sends and receives messages over a WebSocket connection using the specified codec
+type wsTransport struct {
+	conn  *websocket.Conn
+	codec *Codec
+	tap   *rawTap
+
+	// writeMu serializes writes (gorilla connections support only one concurrent writer)
+	writeMu sync.Mutex
+}
+
+var (
+	_ transport    = (*wsTransport)(nil)
+	_ rawTransport = (*wsTransport)(nil)
+)
+
+func (t *wsTransport) Receive(msg *cableMsg) (int, error) {
+	mtype, raw, err := t.conn.ReadMessage()
+	if err != nil {
+		return 0, err
+	}
+
+	t.tap.push(raw, mtype == websocket.BinaryMessage)
+
+	if err := t.codec.DecodeFrame(mtype, raw, msg); err != nil {
+		return len(raw), &decodeError{err}
+	}
+
+	return len(raw), nil
+}
+
+func (t *wsTransport) Send(msg *cableMsg) (int, error) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	return t.codec.Send(t.conn, msg)
+}
+
+func (t *wsTransport) SendRaw(data []byte, binary bool) (int, error) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	return len(data), t.conn.WriteMessage(wsMessageType(binary), data)
+}
+
+func (t *wsTransport) setTap(tap *rawTap) {
+	t.tap = tap
+}
+
+func (t *wsTransport) Close() error {
+	return t.conn.Close()
+}
+
+func wsMessageType(binary bool) int {
+	if binary {
+		return websocket.BinaryMessage
+	}
+
+	return websocket.TextMessage
+}
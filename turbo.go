@@ -0,0 +1,168 @@
+package cable
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/grafana/sobek"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TurboStream represents a parsed <turbo-stream> element
+type TurboStream struct {
+	Action     string            `js:"action"`
+	Target     string            `js:"target"`
+	Targets    string            `js:"targets"`
+	Template   string            `js:"template"`
+	Attributes map[string]string `js:"attributes"`
+}
+
+// ParseTurboStream parses Turbo Stream messages and returns the list of stream actions
+func (c *Cable) ParseTurboStream(msg sobek.Value) ([]*TurboStream, error) {
+	if msg == nil || sobek.IsUndefined(msg) || sobek.IsNull(msg) {
+		return nil, nil
+	}
+
+	return parseTurboStreams(msg.String())
+}
+
+// parseTurboStreams extracts all <turbo-stream> elements from the HTML string
+func parseTurboStreams(source string) ([]*TurboStream, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(source), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []*TurboStream
+
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "turbo-stream" {
+			streams = append(streams, newTurboStream(n))
+			return
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+
+	for _, n := range nodes {
+		visit(n)
+	}
+
+	return streams, nil
+}
+
+func newTurboStream(n *html.Node) *TurboStream {
+	stream := &TurboStream{Attributes: make(map[string]string)}
+
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "action":
+			stream.Action = attr.Val
+		case "target":
+			stream.Target = attr.Val
+		case "targets":
+			stream.Targets = attr.Val
+		default:
+			stream.Attributes[attr.Key] = attr.Val
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Template {
+			stream.Template = innerHTML(child)
+			break
+		}
+	}
+
+	return stream
+}
+
+func innerHTML(n *html.Node) string {
+	var buf bytes.Buffer
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		_ = html.Render(&buf, child)
+	}
+
+	return buf.String()
+}
+
+// Turbo returns a matcher for Turbo Stream messages to be used with receive functions
+// (e.g., `channel.receive(cable.turbo({action: "append", target: "messages"}))`)
+func (c *Cable) Turbo(cond sobek.Value) (*TurboMatcher, error) {
+	expected, err := attrsFromValue(c.vu.Runtime(), cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TurboMatcher{expected}, nil
+}
+
+// TurboMatcher matches Turbo Stream messages containing a stream element with the expected attributes:
+// action, target and targets are compared exactly, template must be included in the template contents,
+// any other key is compared with the corresponding element attribute.
+type TurboMatcher struct {
+	expected map[string]interface{}
+}
+
+func (m *TurboMatcher) Match(msg interface{}) bool {
+	msgStr, ok := msg.(string)
+	if !ok {
+		return false
+	}
+
+	streams, err := parseTurboStreams(msgStr)
+	if err != nil {
+		return false
+	}
+
+	for _, stream := range streams {
+		if m.matchStream(stream) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *TurboMatcher) matchStream(stream *TurboStream) bool {
+	for k, v := range m.expected {
+		expected, ok := v.(string)
+		if !ok {
+			return false
+		}
+
+		switch k {
+		case "action":
+			if stream.Action != expected {
+				return false
+			}
+		case "target":
+			if stream.Target != expected {
+				return false
+			}
+		case "targets":
+			if stream.Targets != expected {
+				return false
+			}
+		case "template":
+			if !strings.Contains(stream.Template, expected) {
+				return false
+			}
+		default:
+			if stream.Attributes[k] != expected {
+				return false
+			}
+		}
+	}
+
+	return true
+}
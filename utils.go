@@ -0,0 +1,164 @@
+package cable
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxCompiledCacheSize limits the number of entries in the caches of compiled matchers
+const maxCompiledCacheSize = 1024
+
+// compiledCache is a size-limited cache for compiled matchers (expressions, regular expressions), schemas and parsed transcripts.
+// Sources could be interpolated per VU or iteration, so the cache is reset when it's full
+// instead of growing without limit.
+type compiledCache struct {
+	mu    sync.RWMutex
+	size  int
+	items map[string]interface{}
+}
+
+func newCompiledCache(size int) *compiledCache {
+	return &compiledCache{size: size, items: make(map[string]interface{})}
+}
+
+func (c *compiledCache) get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	val, ok := c.items[key]
+
+	return val, ok
+}
+
+func (c *compiledCache) put(key string, val interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.items) >= c.size {
+		c.items = make(map[string]interface{})
+	}
+
+	c.items[key] = val
+}
+
+func (c *compiledCache) len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.items)
+}
+
+// lookupPath returns the value at the dot-separated path (e.g., "post.createdAt")
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	val := data
+
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		val, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+// deletePath removes the value at the dotted path (if any).
+// The "*" key matches all the object values or array elements.
+func deletePath(data interface{}, path string) {
+	deleteKeys(data, strings.Split(path, "."))
+}
+
+func deleteKeys(data interface{}, keys []string) {
+	key, rest := keys[0], keys[1:]
+
+	switch val := data.(type) {
+	case map[string]interface{}:
+		if key == "*" {
+			for k := range val {
+				if len(rest) == 0 {
+					delete(val, k)
+				} else {
+					deleteKeys(val[k], rest)
+				}
+			}
+			return
+		}
+
+		if len(rest) == 0 {
+			delete(val, key)
+		} else if next, ok := val[key]; ok {
+			deleteKeys(next, rest)
+		}
+	case []interface{}:
+		if key != "*" || len(rest) == 0 {
+			return
+		}
+
+		for _, item := range val {
+			deleteKeys(item, rest)
+		}
+	}
+}
+
+// toFloat64 converts numeric values of any type (JSON, msgpack) to float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// randomID returns a random hex string (used to generate unique identifiers)
+func randomID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// randomUUID returns a random (version 4) UUID
+func randomUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}